@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/n3xem/gh-otui/cache"
+	"github.com/n3xem/gh-otui/cmd"
+	"github.com/n3xem/gh-otui/config"
+	"github.com/n3xem/gh-otui/models"
+)
+
+// runAction runs the configured action bound to args[0] on the repository
+// whose key is the rest of args. The fzf bindings of the actions run it with
+// the key of the current line.
+func runAction(ctx context.Context, opts *options, cfg *config.Config, ws cmd.Workspace, args []string) error {
+	if len(args) > 1 && args[1] == "--" {
+		args = append(args[:1], args[2:]...)
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("usage: gh otui action <key> -- <host/owner/name>")
+	}
+	action, ok := cfg.Action(args[0])
+	if !ok {
+		return fmt.Errorf("no action is bound to %s", args[0])
+	}
+	repo, err := models.ParsePath(cmd.LineKey(strings.Join(args[1:], " ")))
+	if err != nil {
+		return err
+	}
+	// Web の URL は API から取得したリポジトリにしかない
+	if cached, ok := cachedRepository(ctx, repo.Key()); ok {
+		repo = cached
+	}
+	path, _ := cmd.FindClone(repo, ws.Roots())
+	out, err := opts.runner.Run(ctx, cmd.Command{
+		Name: "sh",
+		Args: []string{"-c", action.Run},
+		Env: []string{
+			"GH_OTUI_REPO=" + repo.Key(),
+			"GH_OTUI_HOST=" + repo.Host,
+			"GH_OTUI_OWNER=" + repo.Namespace(),
+			"GH_OTUI_NAME=" + repo.Name,
+			"GH_OTUI_URL=" + repo.HtmlUrl,
+			"GH_OTUI_PATH=" + path,
+		},
+		Stderr: opts.stderr,
+		// 何をするかはユーザー次第なのでドライランでは実行しない
+		Mutates: true,
+	})
+	opts.stdout.Write(out)
+	if err != nil {
+		return fmt.Errorf("action %s failed: %w", action.Key, err)
+	}
+	return nil
+}
+
+// cachedRepository returns the cached repository of key, if any.
+func cachedRepository(ctx context.Context, key string) (models.Repository, bool) {
+	groups, err := cache.FetchRepositories(ctx)
+	if err != nil {
+		return models.Repository{}, false
+	}
+	for _, g := range groups {
+		for _, repo := range g.Repositories() {
+			if repo.Key() == key {
+				return repo, true
+			}
+		}
+	}
+	return models.Repository{}, false
+}
@@ -3,9 +3,11 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -32,18 +34,53 @@ func LoadCache() ([]github.Repository, error) {
 
 type Metadata struct {
 	lastUpdated time.Time
+	// invalidated is set when part of the cache was cleared, so that it is
+	// refreshed on the next run.
+	invalidated bool
+	hostErrors  map[string]string
+	truncations []Truncation
 }
 
-func (m *Metadata) IsStale() bool {
-	return time.Since(m.lastUpdated) > 1*time.Hour
+// IsStale reports whether the cache was last updated more than ttl ago.
+func (m *Metadata) IsStale(ttl time.Duration) bool {
+	return m.invalidated || time.Since(m.lastUpdated) > ttl
 }
 
 func (m *Metadata) Initialized() bool {
 	return !m.lastUpdated.IsZero()
 }
 
+// HostErrors returns the hosts that could not be refreshed by the last update,
+// keyed by host name.
+func (m *Metadata) HostErrors() map[string]string {
+	return m.hostErrors
+}
+
+// Truncations returns the listings that were cut off at the page limit by the
+// last update, so the groups cached from them are incomplete.
+func (m *Metadata) Truncations() []Truncation {
+	return m.truncations
+}
+
+// Truncation is a listing that had more pages than were fetched.
+type Truncation struct {
+	Host     string `json:"host"`
+	Listing  string `json:"listing"`
+	Fetched  int    `json:"fetched_pages"`
+	LastPage int    `json:"last_page"`
+}
+
+// Report is the outcome of a cache update.
+type Report struct {
+	HostErrors  map[string]error
+	Truncations []Truncation
+}
+
 type metadataDTO struct {
-	LastUpdated time.Time `json:"last_updated"`
+	LastUpdated time.Time         `json:"last_updated"`
+	HostErrors  map[string]string `json:"host_errors,omitempty"`
+	Truncations []Truncation      `json:"truncations,omitempty"`
+	Invalidated bool              `json:"invalidated,omitempty"`
 }
 
 func LoadMetadata(ctx context.Context) (*Metadata, error) {
@@ -65,17 +102,72 @@ func LoadMetadata(ctx context.Context) (*Metadata, error) {
 	}
 	md := Metadata{
 		lastUpdated: dto.LastUpdated,
+		hostErrors:  dto.HostErrors,
+		truncations: dto.Truncations,
+		invalidated: dto.Invalidated,
 	}
 	return &md, nil
 }
 
-func Done(ctx context.Context) error {
+// Done marks the cache as updated and records the outcome of the update.
+func Done(ctx context.Context, report Report) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
-	dto := metadataDTO{
+	return saveMetadata(metadataDTO{
 		LastUpdated: time.Now(),
+		HostErrors:  errorMessages(report.HostErrors),
+		Truncations: report.Truncations,
+	})
+}
+
+// Record records the outcome of an update that cached nothing, without
+// touching the last update time.
+func Record(ctx context.Context, report Report) error {
+	md, err := LoadMetadata(ctx)
+	if err != nil {
+		return err
+	}
+	return saveMetadata(metadataDTO{
+		LastUpdated: md.lastUpdated,
+		HostErrors:  errorMessages(report.HostErrors),
+		Truncations: report.Truncations,
+		Invalidated: md.invalidated,
+	})
+}
+
+// AddTruncations records listings cut off at the page limit outside of a full
+// update, replacing earlier records of the same listings.
+func AddTruncations(ctx context.Context, ts []Truncation) error {
+	md, err := LoadMetadata(ctx)
+	if err != nil {
+		return err
 	}
+	truncations := slices.DeleteFunc(md.truncations, func(old Truncation) bool {
+		return slices.ContainsFunc(ts, func(t Truncation) bool {
+			return t.Host == old.Host && t.Listing == old.Listing
+		})
+	})
+	return saveMetadata(metadataDTO{
+		LastUpdated: md.lastUpdated,
+		HostErrors:  md.hostErrors,
+		Truncations: append(truncations, ts...),
+		Invalidated: md.invalidated,
+	})
+}
+
+func errorMessages(errs map[string]error) map[string]string {
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make(map[string]string, len(errs))
+	for k, err := range errs {
+		msgs[k] = err.Error()
+	}
+	return msgs
+}
+
+func saveMetadata(dto metadataDTO) error {
 	b, err := json.Marshal(dto)
 	if err != nil {
 		return fmt.Errorf("failed to create cache: %w", err)
@@ -105,15 +197,227 @@ func hostPath(host string) string {
 	return filepath.Join(root(), host)
 }
 
-func path(host, org string) string {
+// path returns the cache file of a group. Groups listed through a source are
+// stored as {org}.{source}.json so that they never overwrite the owner's own
+// group; owner names cannot contain dots.
+func path(host, org, source string) string {
+	if source != "" {
+		return filepath.Join(hostPath(host), org+"."+source+".json")
+	}
 	return filepath.Join(hostPath(host), org+".json")
 }
 
+// Dir returns the directory the cache is stored in.
+func Dir() string {
+	return root()
+}
+
+// FileError is a cache file that is not valid. Host and Org are set for the
+// files of repository groups.
+type FileError struct {
+	Path string
+	Host string
+	Org  string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// IsHistory reports whether the file is the selection history.
+func (e *FileError) IsHistory() bool {
+	return e.Path == historyPath()
+}
+
+// IsMetadata reports whether the file is the metadata.
+func (e *FileError) IsMetadata() bool {
+	return e.Path == metadataPath()
+}
+
+// Verify reads back every file of the cache. It returns the number of files
+// and an error for each file that is not valid, a *FileError unless the cache
+// directory itself cannot be read.
+func Verify(ctx context.Context) (int, []error) {
+	var n int
+	var errs []error
+	err := filepath.WalkDir(root(), func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == root() {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() || filepath.Ext(p) != ".json" {
+			return nil
+		}
+		n++
+		fileErr := &FileError{Path: p}
+		if dir := filepath.Dir(p); dir != root() {
+			fileErr.Host = filepath.Base(dir)
+			fileErr.Org, _, _ = strings.Cut(strings.TrimSuffix(filepath.Base(p), ".json"), ".")
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			fileErr.Err = err
+			errs = append(errs, fileErr)
+			return nil
+		}
+		if !json.Valid(b) {
+			fileErr.Err = errors.New("invalid JSON")
+			errs = append(errs, fileErr)
+			return nil
+		}
+		// グループのファイルは読み込めることまで確認する
+		if fileErr.Host != "" {
+			_, source, _ := strings.Cut(strings.TrimSuffix(filepath.Base(p), ".json"), ".")
+			if _, err := Load(ctx, fileErr.Host, fileErr.Org, source); err != nil {
+				fileErr.Err = err
+				errs = append(errs, fileErr)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return n, errs
+}
+
+// Clear removes the cached repositories and the metadata. The selection
+// history and the working copy statuses are kept, as they cannot be fetched
+// again.
 func Clear(ctx context.Context) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
-	if err := os.RemoveAll(root()); err != nil {
+	entries, err := os.ReadDir(root())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	keep := []string{historyPath(), statusPath()}
+	for _, e := range entries {
+		p := filepath.Join(root(), e.Name())
+		if slices.Contains(keep, p) {
+			continue
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// ClearGroups removes the cached groups of host and org; an empty host or
+// org matches any. The rest of the cache is kept but refreshed on the next
+// run. It returns the removed files.
+func ClearGroups(ctx context.Context, host, org string) ([]string, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	for _, name := range []string{host, org} {
+		if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+			return nil, fmt.Errorf("invalid name %q", name)
+		}
+	}
+	var removed []string
+	hosts, err := os.ReadDir(root())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	for _, h := range hosts {
+		if !h.IsDir() || (host != "" && !strings.EqualFold(h.Name(), host)) {
+			continue
+		}
+		files, err := os.ReadDir(hostPath(h.Name()))
+		if err != nil {
+			return removed, fmt.Errorf("failed to read cache directory: %w", err)
+		}
+		for _, f := range files {
+			name, _, _ := strings.Cut(strings.TrimSuffix(f.Name(), ".json"), ".")
+			if org != "" && !strings.EqualFold(name, org) {
+				continue
+			}
+			p := filepath.Join(hostPath(h.Name()), f.Name())
+			if err := os.Remove(p); err != nil {
+				return removed, fmt.Errorf("failed to clear cache: %w", err)
+			}
+			removed = append(removed, p)
+		}
+		if org == "" {
+			// 空になったホストのディレクトリも消す
+			os.Remove(hostPath(h.Name()))
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	md, err := LoadMetadata(ctx)
+	if err != nil || !md.Initialized() {
+		return removed, err
+	}
+	return removed, saveMetadata(metadataDTO{
+		LastUpdated: md.lastUpdated,
+		HostErrors:  md.hostErrors,
+		Truncations: md.truncations,
+		Invalidated: true,
+	})
+}
+
+// PruneSource removes the groups of host listed through source whose owner is
+// not in keep, i.e. owners none of whose repositories are listed any more.
+func PruneSource(ctx context.Context, host, source string, keep []string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	files, err := os.ReadDir(hostPath(host))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	for _, f := range files {
+		org, s, _ := strings.Cut(strings.TrimSuffix(f.Name(), ".json"), ".")
+		if s != source || slices.ContainsFunc(keep, func(k string) bool { return strings.EqualFold(k, org) }) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(hostPath(host), f.Name())); err != nil {
+			return fmt.Errorf("failed to prune cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// ClearMetadata removes the metadata, so that the next run fetches the
+// repositories before showing them.
+func ClearMetadata(ctx context.Context) error {
+	return remove(ctx, metadataPath())
+}
+
+// ClearHistory forgets the selected repositories.
+func ClearHistory(ctx context.Context) error {
+	return remove(ctx, historyPath())
+}
+
+func remove(ctx context.Context, p string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to clear cache: %w", err)
 	}
 	return nil
@@ -125,6 +429,9 @@ func FetchRepositories(ctx context.Context) ([]*models.RepositoryGroup, error) {
 	}
 	dirs, err := os.ReadDir(root())
 	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
 		return nil, fmt.Errorf("failed to read cache directory: %w", err)
 	}
 
@@ -139,8 +446,8 @@ func FetchRepositories(ctx context.Context) ([]*models.RepositoryGroup, error) {
 		}
 		for _, file := range files {
 			host := dir.Name()
-			org := strings.TrimSuffix(file.Name(), ".json")
-			repos, err := Load(ctx, host, org)
+			org, source, _ := strings.Cut(strings.TrimSuffix(file.Name(), ".json"), ".")
+			repos, err := Load(ctx, host, org, source)
 			if err != nil {
 				return nil, fmt.Errorf("failed to load cache for %s/%s: %w", host, org, err)
 			}
@@ -154,11 +461,11 @@ type cacheDTO struct {
 	Repositories []models.Repository `json:"repositories"`
 }
 
-func Load(ctx context.Context, host, org string) (*models.RepositoryGroup, error) {
+func Load(ctx context.Context, host, org, source string) (*models.RepositoryGroup, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	p := path(host, org)
+	p := path(host, org, source)
 	b, err := os.ReadFile(p)
 	if err != nil {
 		return nil, err
@@ -171,13 +478,25 @@ func Load(ctx context.Context, host, org string) (*models.RepositoryGroup, error
 	repos := make([]models.Repository, 0, len(dto.Repositories))
 	for _, repo := range dto.Repositories {
 		repos = append(repos, models.Repository{
-			Name:    repo.Name,
-			OrgName: repo.OrgName,
-			Host:    repo.Host,
-			HtmlUrl: repo.HtmlUrl,
+			Name:        repo.Name,
+			OrgName:     repo.OrgName,
+			Host:        repo.Host,
+			HtmlUrl:     repo.HtmlUrl,
+			Description: repo.Description,
+			Language:    repo.Language,
+			Stars:       repo.Stars,
+			PushedAt:    repo.PushedAt,
+			Private:     repo.Private,
+			Archived:    repo.Archived,
+			Sources:     repo.Sources,
 		})
 	}
-	g, err := models.NewRepositoryGroup(repos...)
+	var g *models.RepositoryGroup
+	if source != "" {
+		g, err = models.NewSourceRepositoryGroup(source, repos...)
+	} else {
+		g, err = models.NewOwnerRepositoryGroup(host, org, repos...)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create repository group: %w", err)
 	}
@@ -202,7 +521,7 @@ func Save(ctx context.Context, g *models.RepositoryGroup) error {
 		return fmt.Errorf("failed to create cache directory: %w", err)
 	}
 
-	p := path(g.Host(), g.Organization())
+	p := path(g.Host(), g.Organization(), g.Source())
 	if err := os.WriteFile(p, b, 0644); err != nil {
 		return fmt.Errorf("failed to save cache: %w", err)
 	}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxVisits is the number of selections kept in the history.
+const maxVisits = 1000
+
+// Actions taken on a selected repository.
+const (
+	ActionOpen  = "open"
+	ActionClone = "clone"
+)
+
+// Visit is a selection of a repository.
+type Visit struct {
+	Key    string    `json:"key"`
+	Time   time.Time `json:"time"`
+	Action string    `json:"action,omitempty"`
+}
+
+// History is the list of selections, oldest first.
+type History struct {
+	Visits []Visit `json:"visits"`
+}
+
+func historyPath() string {
+	return filepath.Join(root(), "_history.json")
+}
+
+func LoadHistory(ctx context.Context) (*History, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	var h History
+	b, err := os.ReadFile(historyPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &h, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &h); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", historyPath(), err)
+	}
+	return &h, nil
+}
+
+// Add records a selection of the repository identified by key.
+func (h *History) Add(key, action string, t time.Time) {
+	h.Visits = append(h.Visits, Visit{Key: key, Time: t, Action: action})
+	if len(h.Visits) > maxVisits {
+		h.Visits = h.Visits[len(h.Visits)-maxVisits:]
+	}
+}
+
+// Recent returns the last n distinct visits, most recent first.
+func (h *History) Recent(n int) []Visit {
+	seen := make(map[string]bool)
+	var visits []Visit
+	for i := len(h.Visits) - 1; i >= 0 && len(visits) < n; i-- {
+		v := h.Visits[i]
+		if key := strings.ToLower(v.Key); !seen[key] {
+			seen[key] = true
+			visits = append(visits, v)
+		}
+	}
+	return visits
+}
+
+// Frecency scores the repositories by how often and how recently they were
+// selected, like the address bar of Firefox does: every selection counts,
+// recent ones more. The scores are keyed by the lowercased keys.
+func (h *History) Frecency(now time.Time) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, v := range h.Visits {
+		var weight float64
+		switch age := now.Sub(v.Time); {
+		case age < 4*time.Hour:
+			weight = 100
+		case age < 24*time.Hour:
+			weight = 80
+		case age < 7*24*time.Hour:
+			weight = 60
+		case age < 30*24*time.Hour:
+			weight = 40
+		case age < 90*24*time.Hour:
+			weight = 20
+		default:
+			weight = 10
+		}
+		scores[strings.ToLower(v.Key)] += weight
+	}
+	return scores
+}
+
+func SaveHistory(ctx context.Context, h *History) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("failed to create history: %w", err)
+	}
+	if err := os.MkdirAll(root(), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(historyPath(), b, 0644); err != nil {
+		return fmt.Errorf("failed to save history: %w", err)
+	}
+	return nil
+}
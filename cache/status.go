@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/n3xem/gh-otui/models"
+)
+
+// Status is the state of a local clone as of CheckedAt.
+type Status struct {
+	models.WorkingCopy
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// IsStale reports whether the status should be checked again.
+func (s Status) IsStale() bool {
+	return time.Since(s.CheckedAt) > time.Minute*5
+}
+
+func statusPath() string {
+	return filepath.Join(root(), "_status.json")
+}
+
+// LoadStatuses returns the cached statuses of the local clones by path.
+func LoadStatuses(ctx context.Context) (map[string]Status, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	statuses := make(map[string]Status)
+	b, err := os.ReadFile(statusPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return statuses, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &statuses); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", statusPath(), err)
+	}
+	return statuses, nil
+}
+
+func SaveStatuses(ctx context.Context, statuses map[string]Status) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	b, err := json.Marshal(statuses)
+	if err != nil {
+		return fmt.Errorf("failed to create cache: %w", err)
+	}
+	if err := os.MkdirAll(root(), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(statusPath(), b, 0644); err != nil {
+		return fmt.Errorf("failed to save cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Summary describes what is in the cache.
+type Summary struct {
+	Dir         string            `json:"dir"`
+	LastUpdated time.Time         `json:"last_updated"`
+	Stale       bool              `json:"stale"`
+	HostErrors  map[string]string `json:"host_errors,omitempty"`
+	Truncations []Truncation      `json:"truncations,omitempty"`
+	Groups      []GroupSummary    `json:"groups"`
+	// Size is the disk usage of every file in the cache in bytes, including
+	// the history and the working copy statuses.
+	Size int64 `json:"size"`
+}
+
+// GroupSummary describes the cache file of a group of repositories.
+type GroupSummary struct {
+	Host         string    `json:"host"`
+	Org          string    `json:"org"`
+	Source       string    `json:"source,omitempty"`
+	Repositories int       `json:"repositories"`
+	Size         int64     `json:"size"`
+	ModTime      time.Time `json:"updated"`
+	// Error is why the file could not be read.
+	Error string `json:"error,omitempty"`
+}
+
+// Summarize inspects the cache. The cache is stale when it was last updated
+// more than ttl ago.
+func Summarize(ctx context.Context, ttl time.Duration) (*Summary, error) {
+	md, err := LoadMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &Summary{
+		Dir:         root(),
+		LastUpdated: md.lastUpdated,
+		Stale:       md.Initialized() && md.IsStale(ttl),
+		HostErrors:  md.HostErrors(),
+		Truncations: md.Truncations(),
+		Groups:      []GroupSummary{},
+	}
+	err = filepath.WalkDir(root(), func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == root() {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		s.Size += info.Size()
+		dir := filepath.Dir(p)
+		if dir == root() || filepath.Ext(p) != ".json" {
+			return nil
+		}
+		host := filepath.Base(dir)
+		org, source, _ := strings.Cut(strings.TrimSuffix(filepath.Base(p), ".json"), ".")
+		g := GroupSummary{Host: host, Org: org, Source: source, Size: info.Size(), ModTime: info.ModTime()}
+		if repos, err := Load(ctx, host, org, source); err != nil {
+			g.Error = err.Error()
+		} else {
+			g.Repositories = len(repos.Repositories())
+		}
+		s.Groups = append(s.Groups, g)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(s.Groups, func(a, b GroupSummary) int {
+		return strings.Compare(a.Host+"/"+a.Org+"."+a.Source, b.Host+"/"+b.Org+"."+b.Source)
+	})
+	return s, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/n3xem/gh-otui/cache"
+	"github.com/n3xem/gh-otui/config"
+)
+
+// cacheCommand runs the cache subcommands.
+func cacheCommand(ctx context.Context, opts *options, cfg *config.Config, args []string) error {
+	if len(args) > 0 && args[0] == "status" {
+		return cacheStatus(ctx, opts, cfg, args[1:])
+	}
+	if len(args) > 0 && args[0] == "clear" {
+		return cacheClear(ctx, opts, args[1:])
+	}
+	fmt.Fprintln(opts.stderr, "Usage: gh otui cache status [--json] | clear [--host host] [--org org] [--history] [--metadata]")
+	return fmt.Errorf("invalid cache command")
+}
+
+// cacheClear removes the whole cache, or only the parts selected by the
+// flags so that the rest stays usable while it is refreshed.
+func cacheClear(ctx context.Context, opts *options, args []string) error {
+	fs := flag.NewFlagSet("gh otui cache clear", flag.ContinueOnError)
+	fs.SetOutput(opts.stderr)
+	host := fs.String("host", "", "only clear the repositories cached for `host`")
+	org := fs.String("org", "", "only clear the repositories cached for the organization or user `name`")
+	history := fs.Bool("history", false, "only clear the selection history")
+	metadata := fs.Bool("metadata", false, "only clear the metadata, so that the next run fetches everything before the selector opens")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	if *host == "" && *org == "" && !*history && !*metadata {
+		if err := cache.Clear(ctx); err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+		fmt.Fprintln(opts.stdout, "Cleared the cache")
+		return nil
+	}
+	if *host != "" || *org != "" {
+		removed, err := cache.ClearGroups(ctx, *host, *org)
+		for _, p := range removed {
+			if rel, err := filepath.Rel(cache.Dir(), p); err == nil {
+				p = rel
+			}
+			fmt.Fprintf(opts.stdout, "Cleared %s\n", filepath.ToSlash(p))
+		}
+		if err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return fmt.Errorf("nothing is cached for %s", path.Join(*host, *org))
+		}
+	}
+	if *history {
+		if err := cache.ClearHistory(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintln(opts.stdout, "Cleared the history")
+	}
+	if *metadata {
+		if err := cache.ClearMetadata(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintln(opts.stdout, "Cleared the metadata")
+	}
+	return nil
+}
+
+// cacheStatus reports what is cached per host and owner, and how old it is.
+func cacheStatus(ctx context.Context, opts *options, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("gh otui cache status", flag.ContinueOnError)
+	fs.SetOutput(opts.stderr)
+	asJSON := fs.Bool("json", false, "print the status as JSON")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	s, err := cache.Summarize(ctx, cfg.TTL())
+	if err != nil {
+		return fmt.Errorf("failed to read cache: %w", err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(opts.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+
+	fmt.Fprintf(opts.stdout, "Cache: %s (%s)\n", s.Dir, formatSize(s.Size))
+	switch {
+	case s.LastUpdated.IsZero():
+		fmt.Fprintln(opts.stdout, "Last update: never")
+	case s.Stale:
+		fmt.Fprintf(opts.stdout, "Last update: %s, stale (TTL %s)\n", formatTime(s.LastUpdated), cfg.TTL())
+	default:
+		fmt.Fprintf(opts.stdout, "Last update: %s, fresh (TTL %s)\n", formatTime(s.LastUpdated), cfg.TTL())
+	}
+
+	if len(s.Groups) > 0 {
+		fmt.Fprintln(opts.stdout)
+		tw := tabwriter.NewWriter(opts.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "HOST\tOWNER\tSOURCE\tREPOS\tSIZE\tUPDATED")
+		for _, g := range s.Groups {
+			repos := fmt.Sprint(g.Repositories)
+			if g.Error != "" {
+				repos = "broken"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", g.Host, g.Org, g.Source, repos, formatSize(g.Size), formatTime(g.ModTime))
+		}
+		tw.Flush()
+	}
+
+	var broken []cache.GroupSummary
+	for _, g := range s.Groups {
+		if g.Error != "" {
+			broken = append(broken, g)
+		}
+	}
+	if len(broken) > 0 {
+		fmt.Fprintln(opts.stdout, "\nBroken files:")
+		for _, g := range broken {
+			fmt.Fprintf(opts.stdout, "  ✗ %s/%s: %s\n", g.Host, g.Org, g.Error)
+		}
+	}
+
+	if len(s.HostErrors) > 0 || len(s.Truncations) > 0 {
+		fmt.Fprintln(opts.stdout, "\nLast refresh:")
+		for _, host := range slices.Sorted(maps.Keys(s.HostErrors)) {
+			fmt.Fprintf(opts.stdout, "  ✗ %s: %s\n", host, s.HostErrors[host])
+		}
+		for _, t := range s.Truncations {
+			fmt.Fprintf(opts.stdout, "  ! %s/%s has %d pages but only %d were cached\n", t.Host, t.Listing, t.LastPage, t.Fetched)
+		}
+	}
+	return nil
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/1024/1024)
+}
+
+func formatTime(t time.Time) string {
+	return fmt.Sprintf("%s (%s ago)", t.Local().Format(time.DateTime), time.Since(t).Round(time.Second))
+}
@@ -0,0 +1,60 @@
+// Package cmdtest provides a fake cmd.Runner that records the commands it is
+// asked to run, for tests.
+package cmdtest
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/n3xem/gh-otui/cmd"
+)
+
+// Call is a recorded command with the environment and standard input it was
+// given.
+type Call struct {
+	Env   []string
+	Name  string
+	Args  []string
+	Stdin string
+}
+
+func (c Call) String() string {
+	return strings.Join(append(append(slices.Clone(c.Env), c.Name), c.Args...), " ")
+}
+
+// Recorder records every command and answers with Handler, or with empty
+// output when Handler is nil.
+type Recorder struct {
+	Handler func(c Call) ([]byte, error)
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (r *Recorder) Run(ctx context.Context, c cmd.Command) ([]byte, error) {
+	call := Call{Env: slices.Clone(c.Env), Name: c.Name, Args: slices.Clone(c.Args)}
+	if c.Stdin != nil {
+		b, err := io.ReadAll(c.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		call.Stdin = string(b)
+	}
+	r.mu.Lock()
+	r.calls = append(r.calls, call)
+	r.mu.Unlock()
+	if r.Handler == nil {
+		return nil, nil
+	}
+	return r.Handler(call)
+}
+
+// Calls returns the commands run so far.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.calls)
+}
@@ -5,44 +5,68 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/n3xem/gh-otui/models"
 )
 
-func GetGhqRoot(ctx context.Context) (string, error) {
-	cmd := execCommandContext(ctx, "ghq", "root")
-	out, err := cmd.Output()
+// GetGhqRoots returns every root of ghq, primary first.
+func GetGhqRoots(ctx context.Context, r Runner) ([]string, error) {
+	out, err := r.Run(ctx, Command{Name: "ghq", Args: []string{"root", "--all"}})
 	if err != nil {
-		return "", fmt.Errorf("failed to get ghq root: %w", err)
+		return nil, fmt.Errorf("failed to get ghq root: %w", err)
 	}
-	return strings.TrimSpace(string(out)), nil
+	var roots []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			roots = append(roots, line)
+		}
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("failed to get ghq root: ghq printed no root")
+	}
+	return roots, nil
 }
 
-func FetchGHQRepositories(ctx context.Context) ([]models.Repository, error) {
-	ghqRepos, err := ListGhqRepositories(ctx)
+// FetchClonedRepositories returns the repositories cloned in ws.
+func FetchClonedRepositories(ctx context.Context, ws Workspace) ([]models.Repository, error) {
+	ghqRepos, err := ws.List(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list ghq repositories: %w", err)
+		return nil, fmt.Errorf("failed to list cloned repositories: %w", err)
 	}
 	repos := make([]models.Repository, 0, len(ghqRepos))
 	for _, ghqRepo := range ghqRepos {
 		repo, err := ghqRepo.ToRepository()
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert ghq repository: %w", err)
+			// host/owner/name の形でない作業コピーは選択できないため無視する
+			continue
 		}
 		repos = append(repos, repo)
 	}
 	return repos, nil
 }
 
-func CheckRequiredCommands() error {
-	requiredCommands := []string{"gh", "ghq"}
+// CheckRequiredCommands checks that the commands gh-otui runs are installed.
+// An empty selector means peco or fzf.
+func CheckRequiredCommands(cloneCommand, selector string) error {
+	requiredCommands := []string{"gh", "git"}
+	if cloneCommand != "git" {
+		requiredCommands = append(requiredCommands, cloneCommand)
+	}
 	for _, cmd := range requiredCommands {
 		if _, err := exec.LookPath(cmd); err != nil {
 			return fmt.Errorf("%s command not found", cmd)
 		}
 	}
 
+	if selector != "" {
+		if _, err := exec.LookPath(selector); err != nil {
+			return fmt.Errorf("selector %s not found", selector)
+		}
+		return nil
+	}
 	// Check for peco or fzf
 	if _, err := exec.LookPath("peco"); err != nil {
 		if _, err := exec.LookPath("fzf"); err != nil {
@@ -52,8 +76,73 @@ func CheckRequiredCommands() error {
 	return nil
 }
 
-func RunSelector(ctx context.Context, lines []string) (string, error) {
-	selector := os.Getenv("GH_OTUI_SELECTOR")
+// SelectorOptions customizes a selector run.
+type SelectorOptions struct {
+	// Selector is the selector command. Empty means $GH_OTUI_SELECTOR, or
+	// peco if it is installed, else fzf.
+	Selector string
+	// Reload is a command whose output replaces the list when the reload key
+	// is pressed. Only fzf supports it.
+	Reload string
+	// Pin is a command run with the key of the current line as its last
+	// argument to toggle the pin of the repository, after which the list is replaced by
+	// the output of List. Only fzf supports it.
+	Pin  string
+	List string
+	// Actions are extra key bindings, run with the key of the current line as
+	// their last argument. Only fzf supports them.
+	Actions []Action
+	// Format renders the lines of the repositories. Nil means the default
+	// format.
+	Format *models.LineFormat
+}
+
+// Action runs Command when Key is pressed.
+type Action struct {
+	Key         string
+	Command     string
+	Description string
+}
+
+const (
+	reloadKey = "ctrl-s"
+	pinKey    = "alt-p"
+)
+
+// keyDelimiter separates the hidden key of a selector line from the line
+// shown. Rendered lines never contain tabs since their columns are aligned
+// with spaces.
+const keyDelimiter = "\t"
+
+// SelectorLines renders repos with format, each line prefixed with the key of
+// its repository so that the selection maps back to the repository however the
+// line looks.
+func SelectorLines(repos []models.Repository, format *models.LineFormat) ([]string, error) {
+	lines, err := format.Lines(repos)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range lines {
+		lines[i] = repos[i].Key() + keyDelimiter + line
+	}
+	return lines, nil
+}
+
+// LineKey returns the key of the repository of a selector line, or the line
+// itself if it has no key.
+func LineKey(line string) string {
+	key, _, _ := strings.Cut(line, keyDelimiter)
+	return strings.TrimSpace(key)
+}
+
+// RunSelector lets the user pick one of lines, which are selector lines as
+// SelectorLines renders them, and returns the key of the selected line. peco
+// and fzf hide the keys; other selectors show them in front of the lines.
+func RunSelector(ctx context.Context, r Runner, lines []string, opts SelectorOptions) (string, error) {
+	selector := opts.Selector
+	if selector == "" {
+		selector = os.Getenv("GH_OTUI_SELECTOR")
+	}
 	if selector == "" {
 		if _, err := exec.LookPath("peco"); err == nil {
 			selector = "peco"
@@ -64,58 +153,101 @@ func RunSelector(ctx context.Context, lines []string) (string, error) {
 		}
 	}
 
-	cmd := execCommandContext(ctx, selector)
-	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n"))
-	cmd.Stderr = os.Stderr
-	out, err := cmd.Output()
+	var args []string
+	input := lines
+	if filepath.Base(selector) == "peco" {
+		// peco --null は NUL の前を表示し、後ろを出力する
+		args = append(args, "--null")
+		input = make([]string, len(lines))
+		for i, line := range lines {
+			key, text, _ := strings.Cut(line, keyDelimiter)
+			input[i] = text + "\x00" + key
+		}
+	}
+	if filepath.Base(selector) == "fzf" && slices.ContainsFunc(lines, models.HasANSI) {
+		args = append(args, "--ansi")
+	}
+	if filepath.Base(selector) == "fzf" {
+		args = append(args, "--delimiter", keyDelimiter, "--with-nth", "2..")
+		var header []string
+		if opts.Reload != "" {
+			args = append(args, "--bind", fmt.Sprintf("%s:reload(%s)", reloadKey, opts.Reload))
+			header = append(header, reloadKey+": search GitHub for the query")
+		}
+		if opts.Pin != "" && opts.List != "" {
+			args = append(args, "--bind", fmt.Sprintf("%s:execute-silent(%s {1})+reload(%s)", pinKey, opts.Pin, opts.List))
+			header = append(header, pinKey+": pin/unpin")
+		}
+		for _, a := range opts.Actions {
+			args = append(args, "--bind", fmt.Sprintf("%s:execute(%s {1})", a.Key, a.Command))
+			if a.Description != "" {
+				header = append(header, a.Key+": "+a.Description)
+			}
+		}
+		if len(header) > 0 {
+			args = append(args, "--header", strings.Join(header, ", "))
+		}
+	}
+
+	out, err := r.Run(ctx, Command{
+		Name:   selector,
+		Args:   args,
+		Stdin:  strings.NewReader(strings.Join(input, "\n")),
+		Stderr: os.Stderr,
+	})
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(out)), nil
+	return LineKey(string(out)), nil
 }
 
-func Select(ctx context.Context, repos []models.Repository) (*models.Repository, error) {
-	var lines []string
-	for _, repo := range repos {
-		lines = append(lines, repo.FormattedLine())
+func Select(ctx context.Context, r Runner, repos []models.Repository, opts SelectorOptions) (*models.Repository, error) {
+	format := opts.Format
+	if format == nil {
+		var err error
+		if format, err = models.NewLineFormat("", ""); err != nil {
+			return nil, err
+		}
+	}
+	lines, err := SelectorLines(repos, format)
+	if err != nil {
+		return nil, err
 	}
 
-	selected, err := RunSelector(ctx, lines)
+	key, err := RunSelector(ctx, r, lines, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to run selector: %w", err)
 	}
 
-	if selected == "" {
+	if key == "" {
 		return nil, ErrRepositoryNotSelected
 	}
 
-	for _, repo := range repos {
-		if strings.Contains(repo.FormattedLine(), selected) {
-			return &repo, nil
+	for i := range repos {
+		if repos[i].Key() == key {
+			return &repos[i], nil
 		}
 	}
+	// リロードで追加された検索結果は元の一覧に含まれない
+	if repo, err := models.ParsePath(key); err == nil {
+		return &repo, nil
+	}
 	return nil, fmt.Errorf("selected repository not found")
 }
 
 var ErrRepositoryNotSelected = fmt.Errorf("repository not selected")
 
-func CloneRepository(ctx context.Context, gitURL string) error {
-	cmd := execCommandContext(ctx, "ghq", "get", gitURL)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to clone repository: %s: %w", string(output), err)
-	}
-	return nil
-}
-
 // ClonedGhqRepository represents a git repository managed by ghq
 type ClonedGhqRepository struct {
 	FullPath string
+	// Root is the ghq root the repository is cloned under.
+	Root string
 }
 
-// ListGhqRepositories returns a list of all repositories managed by ghq
-func ListGhqRepositories(ctx context.Context) ([]ClonedGhqRepository, error) {
-	cmd := execCommandContext(ctx, "ghq", "list", "--full-path")
-	out, err := cmd.Output()
+// ListGhqRepositories returns a list of all repositories managed by ghq.
+// The repositories are assigned the one of roots they are cloned under.
+func ListGhqRepositories(ctx context.Context, r Runner, roots []string) ([]ClonedGhqRepository, error) {
+	out, err := r.Run(ctx, Command{Name: "ghq", Args: []string{"list", "--full-path"}})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list repositories: %w", err)
 	}
@@ -124,35 +256,39 @@ func ListGhqRepositories(ctx context.Context) ([]ClonedGhqRepository, error) {
 	var repositories []ClonedGhqRepository
 	for _, path := range paths {
 		if path != "" {
-			repositories = append(repositories, ClonedGhqRepository{FullPath: path})
+			repositories = append(repositories, ClonedGhqRepository{FullPath: path, Root: rootOf(path, roots)})
 		}
 	}
 	return repositories, nil
 }
 
-func (c ClonedGhqRepository) ToRepository() (models.Repository, error) {
-	parts := strings.Split(c.FullPath, "/")
-	if len(parts) < 4 {
-		return models.Repository{}, fmt.Errorf("invalid repository path: %s", c.FullPath)
-	}
-
-	repoName := parts[len(parts)-1]
-	orgName := parts[len(parts)-2]
-	host := parts[len(parts)-3]
-
-	return models.Repository{
-		Name:    repoName,
-		OrgName: orgName,
-		Host:    host,
-		HtmlUrl: fmt.Sprintf("https://%s/%s/%s", host, orgName, repoName),
-		Cloned:  true,
-	}, nil
+// rootOf returns the innermost of roots containing path.
+func rootOf(path string, roots []string) string {
+	var result string
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && filepath.IsLocal(rel) && len(root) > len(result) {
+			result = root
+		}
+	}
+	return result
 }
 
-func execCommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
-	cmd := exec.CommandContext(ctx, name, args...)
-	cmd.Cancel = func() error {
-		return cmd.Process.Signal(os.Interrupt)
+// ToRepository parses the path of the repository relative to its root as
+// host/owner/name, with any nested namespaces between the owner and the
+// name. The web URL is not guessed since the host may not be a GitHub host.
+func (c ClonedGhqRepository) ToRepository() (models.Repository, error) {
+	if c.Root == "" {
+		return models.Repository{}, fmt.Errorf("repository %s is not under a ghq root", c.FullPath)
+	}
+	rel, err := filepath.Rel(c.Root, c.FullPath)
+	if err != nil || !filepath.IsLocal(rel) {
+		return models.Repository{}, fmt.Errorf("repository %s is not under %s", c.FullPath, c.Root)
+	}
+	repo, err := models.ParsePath(filepath.ToSlash(rel))
+	if err != nil {
+		return models.Repository{}, fmt.Errorf("invalid repository path: %w", err)
 	}
-	return cmd
+	repo.Cloned = true
+	return repo, nil
 }
@@ -0,0 +1,349 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/n3xem/gh-otui/cmd"
+	"github.com/n3xem/gh-otui/cmd/cmdtest"
+	"github.com/n3xem/gh-otui/models"
+)
+
+func TestSelect(t *testing.T) {
+	t.Setenv("GH_OTUI_SELECTOR", "fzf")
+	repos := []models.Repository{
+		{Host: "github.com", OrgName: "acme", Name: "api"},
+		{Host: "github.com", OrgName: "acme", Name: "web", Cloned: true},
+	}
+	r := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+		return []byte("github.com/acme/web\t✓ github.com/acme/web\n"), nil
+	}}
+
+	selected, err := cmd.Select(context.Background(), r, repos, cmd.SelectorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selected.Name != "web" {
+		t.Errorf("selected %s, want web", selected.Name)
+	}
+	calls := r.Calls()
+	if len(calls) != 1 || calls[0].Name != "fzf" {
+		t.Fatalf("calls = %v, want one fzf call", calls)
+	}
+	if want := "github.com/acme/api\t  github.com/acme/api\ngithub.com/acme/web\t✓ github.com/acme/web"; calls[0].Stdin != want {
+		t.Errorf("selector input = %q, want %q", calls[0].Stdin, want)
+	}
+}
+
+func TestSelectHostlessLines(t *testing.T) {
+	format, err := models.NewLineFormat("{{.Mark}} {{.Namespace}}/{{.Name}}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ホストを表示しないと同じ行が並ぶ
+	repos := []models.Repository{
+		{Host: "github.com", OrgName: "acme", Name: "api"},
+		{Host: "ghe.example.com", OrgName: "acme", Name: "api"},
+	}
+
+	t.Run("fzf", func(t *testing.T) {
+		r := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+			_, second, _ := strings.Cut(c.Stdin, "\n")
+			return []byte(second + "\n"), nil
+		}}
+		selected, err := cmd.Select(context.Background(), r, repos, cmd.SelectorOptions{Selector: "fzf", Format: format})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if selected.Host != "ghe.example.com" {
+			t.Errorf("selected %s, want ghe.example.com/acme/api", selected.Key())
+		}
+		if want := "github.com/acme/api\t  acme/api\nghe.example.com/acme/api\t  acme/api"; r.Calls()[0].Stdin != want {
+			t.Errorf("selector input = %q, want %q", r.Calls()[0].Stdin, want)
+		}
+	})
+
+	t.Run("peco", func(t *testing.T) {
+		r := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+			_, second, _ := strings.Cut(c.Stdin, "\n")
+			_, key, _ := strings.Cut(second, "\x00")
+			return []byte(key + "\n"), nil
+		}}
+		selected, err := cmd.Select(context.Background(), r, repos, cmd.SelectorOptions{Selector: "peco", Format: format})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if selected.Host != "ghe.example.com" {
+			t.Errorf("selected %s, want ghe.example.com/acme/api", selected.Key())
+		}
+		call := r.Calls()[0]
+		if !slices.Equal(call.Args, []string{"--null"}) {
+			t.Errorf("peco args = %q, want --null", call.Args)
+		}
+		if want := "  acme/api\x00github.com/acme/api\n  acme/api\x00ghe.example.com/acme/api"; call.Stdin != want {
+			t.Errorf("selector input = %q, want %q", call.Stdin, want)
+		}
+	})
+}
+
+func TestSelectOtherSelector(t *testing.T) {
+	repos := []models.Repository{
+		{Host: "github.com", OrgName: "acme", Name: "api"},
+		{Host: "github.com", OrgName: "acme", Name: "web"},
+	}
+	// sk などはキーの付いた行をそのまま出力する
+	r := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+		_, second, _ := strings.Cut(c.Stdin, "\n")
+		return []byte(second + "\n"), nil
+	}}
+	selected, err := cmd.Select(context.Background(), r, repos, cmd.SelectorOptions{Selector: "sk", Reload: "gh-otui search --print -- {q}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selected.Name != "web" {
+		t.Errorf("selected %s, want web", selected.Name)
+	}
+	call := r.Calls()[0]
+	if call.Name != "sk" || len(call.Args) != 0 {
+		t.Errorf("ran %s, want sk without fzf or peco flags", call)
+	}
+	if want := "github.com/acme/api\t  github.com/acme/api\ngithub.com/acme/web\t  github.com/acme/web"; call.Stdin != want {
+		t.Errorf("selector input = %q, want %q", call.Stdin, want)
+	}
+}
+
+func TestSelectNothing(t *testing.T) {
+	t.Setenv("GH_OTUI_SELECTOR", "peco")
+	r := &cmdtest.Recorder{}
+	_, err := cmd.Select(context.Background(), r, []models.Repository{{Host: "github.com", OrgName: "acme", Name: "api"}}, cmd.SelectorOptions{})
+	if !errors.Is(err, cmd.ErrRepositoryNotSelected) {
+		t.Errorf("err = %v, want ErrRepositoryNotSelected", err)
+	}
+}
+
+func TestListGhqRepositories(t *testing.T) {
+	r := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+		return []byte("/src/github.com/acme/api\n/src/work/gitlab.com/group/sub/repo\n/src/stray\n"), nil
+	}}
+	ghqRepos, err := cmd.ListGhqRepositories(context.Background(), r, []string{"/src", "/src/work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Calls()[0].String(); got != "ghq list --full-path" {
+		t.Errorf("ran %q", got)
+	}
+	if len(ghqRepos) != 3 || ghqRepos[0].Root != "/src" || ghqRepos[1].Root != "/src/work" {
+		t.Fatalf("repositories = %v", ghqRepos)
+	}
+
+	var got []models.Repository
+	for _, ghqRepo := range ghqRepos {
+		if repo, err := ghqRepo.ToRepository(); err == nil {
+			got = append(got, repo)
+		}
+	}
+	want := []models.Repository{
+		{Host: "github.com", OrgName: "acme", Name: "api", Cloned: true},
+		{Host: "gitlab.com", OrgName: "group", Subgroup: "sub", Name: "repo", Cloned: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parsed %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].Host != want[i].Host || got[i].FullName() != want[i].FullName() || got[i].OrgName != want[i].OrgName || got[i].HtmlUrl != "" {
+			t.Errorf("parsed %+v, want %+v", got[i], want[i])
+		}
+	}
+	format, err := models.NewLineFormat("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines, _ := format.Lines(got[1:2]); lines[0] != "✓ gitlab.com/group/sub/repo" {
+		t.Errorf("line = %q", lines[0])
+	}
+	if url := got[1].GetGitURL(); url != "git@gitlab.com:group/sub/repo" {
+		t.Errorf("GetGitURL() = %q", url)
+	}
+}
+
+func TestCloneDryRun(t *testing.T) {
+	t.Setenv("GHQ_ROOT", "/src")
+	var out bytes.Buffer
+	r := &cmdtest.Recorder{}
+	ws, err := cmd.NewWorkspace(context.Background(), cmd.DryRunRunner{Out: &out, Next: r}, cmd.WorkspaceOptions{Command: "git"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := models.Repository{Host: "github.com", OrgName: "acme", Name: "api"}
+	if err := ws.Clone(context.Background(), repo, "/src"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := r.Calls(); len(calls) != 0 {
+		t.Errorf("calls = %v, want none", calls)
+	}
+	if got := strings.TrimSpace(out.String()); got != "+ git clone git@github.com:acme/api /src/github.com/acme/api" {
+		t.Errorf("printed %q", got)
+	}
+}
+
+func TestWorkingCopyStatuses(t *testing.T) {
+	r := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+		switch c.Args[1] {
+		case "/src/api":
+			return []byte("# branch.oid 1234\n# branch.head main\n# branch.upstream origin/main\n# branch.ab +2 -1\n1 .M N... 100644 100644 100644 1234 1234 main.go\n"), nil
+		case "/src/web":
+			return []byte("# branch.oid 1234\n# branch.head feature\n"), nil
+		}
+		return nil, errors.New("not a git repository")
+	}}
+	statuses := cmd.WorkingCopyStatuses(context.Background(), r, []string{"/src/api", "/src/web", "/src/notes"})
+	want := map[string]models.WorkingCopy{
+		"/src/api": {Branch: "main", Dirty: true, Ahead: 2, Behind: 1},
+		"/src/web": {Branch: "feature"},
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("statuses = %v, want %v", statuses, want)
+	}
+	for dir, status := range want {
+		if statuses[dir] != status {
+			t.Errorf("status of %s = %+v, want %+v", dir, statuses[dir], status)
+		}
+	}
+	if got := statuses["/src/api"].String(); got != "main* ↑2 ↓1" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestSelectWithLineFormat(t *testing.T) {
+	t.Setenv("GH_OTUI_SELECTOR", "fzf")
+	format, err := models.NewLineFormat(`{{.Mark}} {{.FullName}}	{{color "cyan" .Language}}	{{.Stars}}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos := []models.Repository{
+		{Host: "github.com", OrgName: "acme", Name: "api", Language: "Go", Stars: 12},
+		{Host: "github.com", OrgName: "acme", Name: "website", Language: "TypeScript", Stars: 3},
+	}
+	r := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+		// fzf --ansi は色を除いた行を出力する
+		first, _, _ := strings.Cut(c.Stdin, "\n")
+		return []byte(models.StripANSI(first) + "\n"), nil
+	}}
+
+	selected, err := cmd.Select(context.Background(), r, repos, cmd.SelectorOptions{Format: format})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selected.Name != "api" {
+		t.Errorf("selected %s, want api", selected.Name)
+	}
+	call := r.Calls()[0]
+	if !slices.Contains(call.Args, "--ansi") {
+		t.Errorf("fzf was run without --ansi: %v", call.Args)
+	}
+	want := "github.com/acme/api\t  acme/api      \x1b[36mGo\x1b[0m          12\ngithub.com/acme/website\t  acme/website  \x1b[36mTypeScript\x1b[0m  3"
+	if call.Stdin != want {
+		t.Errorf("selector input = %q, want %q", call.Stdin, want)
+	}
+}
+
+func TestSelectWithTheme(t *testing.T) {
+	t.Setenv("GH_OTUI_SELECTOR", "fzf")
+	repos := []models.Repository{
+		{Host: "github.com", OrgName: "acme", Name: "api", Private: true, Cloned: true},
+		{Host: "github.com", OrgName: "acme", Name: "legacy", Archived: true},
+	}
+	selectFirst := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+		first, _, _ := strings.Cut(c.Stdin, "\n")
+		return []byte(models.StripANSI(first) + "\n"), nil
+	}}
+
+	t.Run("colors", func(t *testing.T) {
+		format, err := models.NewLineFormat("", "default")
+		if err != nil {
+			t.Fatal(err)
+		}
+		selected, err := cmd.Select(context.Background(), selectFirst, repos, cmd.SelectorOptions{Format: format})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if selected.Name != "api" {
+			t.Errorf("selected %s, want api", selected.Name)
+		}
+		lines, _ := format.Lines(repos)
+		if want := "\x1b[32m✓\x1b[0m \x1b[90mgithub.com\x1b[0m/\x1b[34macme\x1b[0m/\x1b[1mapi\x1b[0m \x1b[33m🔒\x1b[0m"; lines[0] != want {
+			t.Errorf("line = %q, want %q", lines[0], want)
+		}
+		if want := "  github.com/acme/legacy 📦"; models.StripANSI(lines[1]) != want {
+			t.Errorf("line = %q, want %q", models.StripANSI(lines[1]), want)
+		}
+	})
+
+	t.Run("NO_COLOR", func(t *testing.T) {
+		t.Setenv("NO_COLOR", "1")
+		format, err := models.NewLineFormat("", "default")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines, _ := format.Lines(repos)
+		if slices.ContainsFunc(lines, models.HasANSI) {
+			t.Errorf("lines are colored: %q", lines)
+		}
+		if lines[0] != "✓ github.com/acme/api 🔒" {
+			t.Errorf("line = %q", lines[0])
+		}
+	})
+
+	t.Run("no theme", func(t *testing.T) {
+		format, err := models.NewLineFormat("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines, _ := format.Lines(append(repos, models.Repository{Host: "github.com", OrgName: "acme", Name: "tools", Sources: []string{"starred", "watching"}}))
+		want := []string{"✓ github.com/acme/api", "  github.com/acme/legacy", "  github.com/acme/tools [starred,watching]"}
+		if !slices.Equal(lines, want) {
+			t.Errorf("lines = %q, want %q", lines, want)
+		}
+	})
+}
+
+func TestSelectPinBinding(t *testing.T) {
+	t.Setenv("GH_OTUI_SELECTOR", "fzf")
+	repos := []models.Repository{{Host: "github.com", OrgName: "acme", Name: "api"}}
+	r := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+		return []byte("  github.com/acme/api\n"), nil
+	}}
+	_, err := cmd.Select(context.Background(), r, repos, cmd.SelectorOptions{Pin: "gh-otui pin --toggle --", List: "gh-otui search --print"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := r.Calls()[0].Args
+	if want := "alt-p:execute-silent(gh-otui pin --toggle -- {1})+reload(gh-otui search --print)"; !slices.Contains(args, want) {
+		t.Errorf("fzf args = %q, want binding %q", args, want)
+	}
+}
+
+func TestSelectActions(t *testing.T) {
+	repos := []models.Repository{{Host: "github.com", OrgName: "acme", Name: "api"}}
+	r := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+		return []byte("  github.com/acme/api\n"), nil
+	}}
+	_, err := cmd.Select(context.Background(), r, repos, cmd.SelectorOptions{
+		Selector: "fzf",
+		Actions:  []cmd.Action{{Key: "ctrl-o", Command: "gh-otui action ctrl-o --", Description: "browse"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := r.Calls()[0]
+	if call.Name != "fzf" {
+		t.Errorf("ran %s, want fzf", call.Name)
+	}
+	if want := []string{"--delimiter", "\t", "--with-nth", "2..", "--bind", "ctrl-o:execute(gh-otui action ctrl-o -- {1})", "--header", "ctrl-o: browse"}; !slices.Equal(call.Args, want) {
+		t.Errorf("fzf args = %q, want %q", call.Args, want)
+	}
+}
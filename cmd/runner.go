@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Command is an invocation of an external command such as ghq or the selector.
+type Command struct {
+	Name string
+	Args []string
+	// Env holds "KEY=value" pairs added to the environment of the command.
+	Env []string
+	// Stdin is the standard input of the command. It may be nil.
+	Stdin io.Reader
+	// Stderr receives the standard error of the command. When nil, it is
+	// captured and reported in the error of a failed command.
+	Stderr io.Writer
+	// Mutates tells commands that change the filesystem, such as clones,
+	// from commands that only read.
+	Mutates bool
+}
+
+func (c Command) String() string {
+	words := make([]string, 0, len(c.Env)+1+len(c.Args))
+	for _, env := range c.Env {
+		k, v, _ := strings.Cut(env, "=")
+		words = append(words, k+"="+ShellQuote(v))
+	}
+	words = append(words, ShellQuote(c.Name))
+	for _, arg := range c.Args {
+		words = append(words, ShellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// ShellQuote quotes w for sh when it contains characters the shell would
+// interpret.
+func ShellQuote(w string) string {
+	if w == "" || strings.ContainsAny(w, " \t\n'\"\\$`|&;<>()*?[]{}~#") {
+		return "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+	}
+	return w
+}
+
+// Runner runs external commands and returns their standard output.
+type Runner interface {
+	Run(ctx context.Context, c Command) ([]byte, error)
+}
+
+// ExecRunner runs commands as child processes.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, c Command) ([]byte, error) {
+	cmd := execCommandContext(ctx, c.Name, c.Args...)
+	cmd.Stdin = c.Stdin
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if c.Stderr != nil {
+		cmd.Stderr = c.Stderr
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("%s: %w", msg, err)
+		}
+		return out, err
+	}
+	return out, nil
+}
+
+// DryRunRunner prints the commands that mutate something to Out instead of
+// running them, and passes the other commands to Next unprinted, so that
+// gh-otui can show what it would do.
+type DryRunRunner struct {
+	Out  io.Writer
+	Next Runner
+}
+
+func (r DryRunRunner) Run(ctx context.Context, c Command) ([]byte, error) {
+	if !c.Mutates {
+		return r.Next.Run(ctx, c)
+	}
+	fmt.Fprintf(r.Out, "+ %s\n", c)
+	return nil, nil
+}
+
+func execCommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	return cmd
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/n3xem/gh-otui/models"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// statusConcurrency is the number of git status commands run at once.
+const statusConcurrency = 8
+
+// WorkingCopyStatuses checks the working copies in dirs concurrently and
+// returns the statuses by directory. Working copies that could not be checked
+// before ctx is done, or are not git repositories, are left out.
+func WorkingCopyStatuses(ctx context.Context, r Runner, dirs []string) map[string]models.WorkingCopy {
+	type result struct {
+		dir    string
+		status models.WorkingCopy
+		ok     bool
+	}
+	p := pool.NewWithResults[result]().WithContext(ctx).WithMaxGoroutines(statusConcurrency)
+	for _, dir := range dirs {
+		p.Go(func(ctx context.Context) (result, error) {
+			status, err := WorkingCopyStatus(ctx, r, dir)
+			return result{dir: dir, status: status, ok: err == nil}, nil
+		})
+	}
+	results, _ := p.Wait()
+	statuses := make(map[string]models.WorkingCopy, len(results))
+	for _, res := range results {
+		if res.ok {
+			statuses[res.dir] = res.status
+		}
+	}
+	return statuses
+}
+
+// WorkingCopyStatus reads the branch, changes and upstream distance of the
+// git working copy in dir.
+func WorkingCopyStatus(ctx context.Context, r Runner, dir string) (models.WorkingCopy, error) {
+	out, err := r.Run(ctx, Command{Name: "git", Args: []string{"-C", dir, "status", "--porcelain=v2", "--branch"}})
+	if err != nil {
+		return models.WorkingCopy{}, fmt.Errorf("failed to get status of %s: %w", dir, err)
+	}
+	return parseStatus(string(out)), nil
+}
+
+func parseStatus(out string) models.WorkingCopy {
+	var status models.WorkingCopy
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.head "):
+			status.Branch = strings.TrimPrefix(line, "# branch.head ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			fmt.Sscanf(strings.TrimPrefix(line, "# branch.ab "), "+%d -%d", &status.Ahead, &status.Behind)
+		case line != "" && !strings.HasPrefix(line, "#"):
+			status.Dirty = true
+		}
+	}
+	return status
+}
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/n3xem/gh-otui/models"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// Workspace is where repositories are cloned, laid out as
+// root/host/owner/name like ghq does.
+type Workspace interface {
+	// Roots returns the roots clones are looked up in, primary first.
+	Roots() []string
+	// List returns the repositories cloned under the roots.
+	List(ctx context.Context) ([]ClonedGhqRepository, error)
+	// Clone clones repo under root.
+	Clone(ctx context.Context, repo models.Repository, root string) error
+}
+
+// WorkspaceOptions configures a workspace.
+type WorkspaceOptions struct {
+	// Command clones the repositories: "git" (the default) or "ghq".
+	Command string
+	// Protocol is the protocol of the clone URLs: "ssh" (the default) or
+	// "https".
+	Protocol string
+	// ExtraRoots are where clones are also looked up besides the ghq roots.
+	ExtraRoots []string
+}
+
+// NewWorkspace returns the workspace that clones with opts.Command.
+func NewWorkspace(ctx context.Context, r Runner, opts WorkspaceOptions) (Workspace, error) {
+	if opts.Command == "ghq" {
+		roots, err := GetGhqRoots(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		return &ghqWorkspace{runner: r, roots: roots, extra: extraOnly(roots, opts.ExtraRoots), protocol: opts.Protocol}, nil
+	}
+	roots, err := GhqRoots(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return &gitWorkspace{runner: r, roots: append(roots, extraOnly(roots, opts.ExtraRoots)...), protocol: opts.Protocol}, nil
+}
+
+func extraOnly(roots, extra []string) []string {
+	var results []string
+	for _, root := range extra {
+		if !slices.Contains(roots, root) && !slices.Contains(results, root) {
+			results = append(results, root)
+		}
+	}
+	return results
+}
+
+// FindClone returns the path of the clone of repo in the first of roots that
+// has one.
+func FindClone(repo models.Repository, roots []string) (string, bool) {
+	for _, root := range roots {
+		path, err := repo.GetClonePath(root)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// GhqRoots returns the roots ghq would use, primary first, without running
+// ghq: $GHQ_ROOT, or the ghq.root values of git config with the last one
+// primary, or ~/ghq.
+func GhqRoots(ctx context.Context, r Runner) ([]string, error) {
+	if env := os.Getenv("GHQ_ROOT"); env != "" {
+		return absPaths(filepath.SplitList(env))
+	}
+	out, err := r.Run(ctx, Command{Name: "git", Args: []string{"config", "--path", "--get-all", "ghq.root"}})
+	// git config は値がないと終了コード1を返す
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return nil, fmt.Errorf("failed to read ghq.root from git config: %w", err)
+	}
+	var roots []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			roots = append(roots, line)
+		}
+	}
+	slices.Reverse(roots)
+	if len(roots) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		roots = []string{filepath.Join(home, "ghq")}
+	}
+	return absPaths(roots)
+}
+
+func absPaths(paths []string) ([]string, error) {
+	results := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ghq root %s: %w", p, err)
+		}
+		if !slices.Contains(results, abs) {
+			results = append(results, abs)
+		}
+	}
+	return results, nil
+}
+
+type gitWorkspace struct {
+	runner   Runner
+	roots    []string
+	protocol string
+}
+
+func (w *gitWorkspace) Roots() []string {
+	return w.roots
+}
+
+func (w *gitWorkspace) List(ctx context.Context) ([]ClonedGhqRepository, error) {
+	return walkRoots(ctx, w.roots)
+}
+
+func (w *gitWorkspace) Clone(ctx context.Context, repo models.Repository, root string) error {
+	path, err := repo.GetClonePath(root)
+	if err != nil {
+		return err
+	}
+	if _, err := w.runner.Run(ctx, Command{Name: "git", Args: []string{"clone", repo.CloneURL(w.protocol), path}, Mutates: true}); err != nil {
+		return fmt.Errorf("failed to clone repository: %w", err)
+	}
+	return nil
+}
+
+// vcsDirs mark the top of a working copy, as in ghq.
+var vcsDirs = []string{".git", ".hg", ".svn", "_darcs", ".fslckout", "_FOSSIL_", ".bzr"}
+
+// walkConcurrency is the number of owner directories walked at once.
+const walkConcurrency = 8
+
+func walkRoots(ctx context.Context, roots []string) ([]ClonedGhqRepository, error) {
+	var repositories []ClonedGhqRepository
+	for _, root := range roots {
+		repos, err := walkRoot(ctx, root)
+		if err != nil {
+			return nil, err
+		}
+		repositories = append(repositories, repos...)
+	}
+	return repositories, nil
+}
+
+// walkRoot finds the working copies under root without descending into them.
+// The owner directories (root/host/owner) are walked in parallel.
+func walkRoot(ctx context.Context, root string) ([]ClonedGhqRepository, error) {
+	hosts, err := subdirs(root)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list repositories under %s: %w", root, err)
+	}
+	p := pool.NewWithResults[[]ClonedGhqRepository]().WithErrors().WithContext(ctx).WithMaxGoroutines(walkConcurrency)
+	for _, host := range hosts {
+		owners, err := subdirs(host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repositories under %s: %w", root, err)
+		}
+		for _, owner := range owners {
+			p.Go(func(ctx context.Context) ([]ClonedGhqRepository, error) {
+				return walkDir(ctx, root, owner)
+			})
+		}
+	}
+	results, err := p.Wait()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories under %s: %w", root, err)
+	}
+	repositories := slices.Concat(results...)
+	slices.SortFunc(repositories, func(a, b ClonedGhqRepository) int {
+		return strings.Compare(a.FullPath, b.FullPath)
+	})
+	return repositories, nil
+}
+
+// subdirs returns the directories in dir that are not working copies, which
+// cannot hold repositories in the ghq layout at the host and owner levels.
+func subdirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if e.IsDir() && !isWorkingCopy(path) {
+			dirs = append(dirs, path)
+		}
+	}
+	return dirs, nil
+}
+
+func walkDir(ctx context.Context, root, dir string) ([]ClonedGhqRepository, error) {
+	var repositories []ClonedGhqRepository
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.IsDir() || path == dir {
+			return nil
+		}
+		if isWorkingCopy(path) {
+			repositories = append(repositories, ClonedGhqRepository{FullPath: path, Root: root})
+			return fs.SkipDir
+		}
+		return nil
+	})
+	return repositories, err
+}
+
+func isWorkingCopy(dir string) bool {
+	for _, vcs := range vcsDirs {
+		if _, err := os.Lstat(filepath.Join(dir, vcs)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+type ghqWorkspace struct {
+	runner Runner
+	roots  []string
+	// extra holds the roots ghq does not know about.
+	extra    []string
+	protocol string
+}
+
+func (w *ghqWorkspace) Roots() []string {
+	return append(slices.Clone(w.roots), w.extra...)
+}
+
+func (w *ghqWorkspace) List(ctx context.Context) ([]ClonedGhqRepository, error) {
+	repos, err := ListGhqRepositories(ctx, w.runner, w.roots)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := walkRoots(ctx, w.extra)
+	if err != nil {
+		return nil, err
+	}
+	return append(repos, extra...), nil
+}
+
+func (w *ghqWorkspace) Clone(ctx context.Context, repo models.Repository, root string) error {
+	c := Command{Name: "ghq", Args: []string{"get", repo.CloneURL(w.protocol)}, Mutates: true}
+	if root != w.roots[0] {
+		// ghq は GHQ_ROOT の先頭にクローンする
+		c.Env = []string{"GHQ_ROOT=" + root}
+	}
+	if _, err := w.runner.Run(ctx, c); err != nil {
+		return fmt.Errorf("failed to clone repository: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package cmd_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/n3xem/gh-otui/cmd"
+	"github.com/n3xem/gh-otui/cmd/cmdtest"
+	"github.com/n3xem/gh-otui/models"
+)
+
+func TestGhqRoots(t *testing.T) {
+	t.Run("GHQ_ROOT", func(t *testing.T) {
+		t.Setenv("GHQ_ROOT", "/src"+string(os.PathListSeparator)+"/work")
+		roots, err := cmd.GhqRoots(context.Background(), &cmdtest.Recorder{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"/src", "/work"}; !slices.Equal(roots, want) {
+			t.Errorf("roots = %q, want %q", roots, want)
+		}
+	})
+
+	t.Run("git config", func(t *testing.T) {
+		t.Setenv("GHQ_ROOT", "")
+		r := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+			return []byte("/work\n/src\n"), nil
+		}}
+		roots, err := cmd.GhqRoots(context.Background(), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 最後の ghq.root が主となる
+		if want := []string{"/src", "/work"}; !slices.Equal(roots, want) {
+			t.Errorf("roots = %q, want %q", roots, want)
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("GHQ_ROOT", "")
+		roots, err := cmd.GhqRoots(context.Background(), &cmdtest.Recorder{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{filepath.Join(home, "ghq")}; !slices.Equal(roots, want) {
+			t.Errorf("roots = %q, want %q", roots, want)
+		}
+	})
+}
+
+func TestWorkspace(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GHQ_ROOT", root)
+	for _, dir := range []string{
+		"github.com/acme/api/.git",
+		"github.com/acme/api/vendor/lib/.git",
+		"gitlab.com/group/sub/repo/.git",
+		"github.com/acme/notes",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &cmdtest.Recorder{}
+	ws, err := cmd.NewWorkspace(context.Background(), r, cmd.WorkspaceOptions{Command: "git"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repos, err := ws.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, repo := range repos {
+		rel, _ := filepath.Rel(root, repo.FullPath)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	if want := []string{"github.com/acme/api", "gitlab.com/group/sub/repo"}; !slices.Equal(got, want) {
+		t.Errorf("listed %q, want %q", got, want)
+	}
+
+	repo, _ := repos[0].ToRepository()
+	if err := ws.Clone(context.Background(), repo, root); err != nil {
+		t.Fatal(err)
+	}
+	want := "git clone git@github.com:acme/api " + filepath.Join(root, "github.com", "acme", "api")
+	if calls := r.Calls(); len(calls) != 1 || calls[0].String() != want {
+		t.Errorf("calls = %v, want %q", calls, want)
+	}
+}
+
+func TestGhqWorkspace(t *testing.T) {
+	r := &cmdtest.Recorder{Handler: func(c cmdtest.Call) ([]byte, error) {
+		if c.String() == "ghq root --all" {
+			return []byte("/src\n/work\n"), nil
+		}
+		return nil, nil
+	}}
+	extra := t.TempDir()
+	ws, err := cmd.NewWorkspace(context.Background(), r, cmd.WorkspaceOptions{Command: "ghq", Protocol: "https", ExtraRoots: []string{"/work", extra}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/src", "/work", extra}; !slices.Equal(ws.Roots(), want) {
+		t.Errorf("roots = %q, want %q", ws.Roots(), want)
+	}
+
+	repo := models.Repository{Host: "github.com", OrgName: "acme", Name: "api"}
+	if err := ws.Clone(context.Background(), repo, "/src"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.Clone(context.Background(), repo, "/work"); err != nil {
+		t.Fatal(err)
+	}
+	calls := r.Calls()
+	if len(calls) != 3 || calls[1].String() != "ghq get https://github.com/acme/api.git" || calls[2].String() != "GHQ_ROOT=/work ghq get https://github.com/acme/api.git" {
+		t.Fatalf("calls = %v", calls)
+	}
+}
+
+func TestFindClone(t *testing.T) {
+	primary, secondary := t.TempDir(), t.TempDir()
+	clone := filepath.Join(secondary, "github.com", "acme", "api")
+	if err := os.MkdirAll(clone, 0755); err != nil {
+		t.Fatal(err)
+	}
+	repo := models.Repository{Host: "github.com", OrgName: "acme", Name: "api"}
+	if path, ok := cmd.FindClone(repo, []string{primary, secondary}); !ok || path != clone {
+		t.Errorf("FindClone() = %q, %v, want %q", path, ok, clone)
+	}
+	repo.Name = "web"
+	if _, ok := cmd.FindClone(repo, []string{primary, secondary}); ok {
+		t.Error("found a clone of a repository that is not cloned")
+	}
+}
@@ -0,0 +1,481 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	ghconfig "github.com/cli/go-gh/v2/pkg/config"
+	"github.com/n3xem/gh-otui/github"
+	"github.com/n3xem/gh-otui/models"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Hosts Patterns `yaml:"hosts,omitempty"`
+	Orgs  Patterns `yaml:"orgs,omitempty"`
+	// ExtraOwners lists organizations and users per host whose repositories
+	// are fetched even though the login user is not a member of them.
+	ExtraOwners map[string][]string `yaml:"extra_owners,omitempty"`
+	// Sources lists additional listings to fetch repositories from:
+	// "starred" and "watching".
+	Sources []string `yaml:"sources,omitempty"`
+	// MaxPages is the number of pages of 100 repositories fetched from a
+	// single listing. Zero means github.DefaultMaxPages.
+	MaxPages int `yaml:"max_pages,omitempty"`
+	// CloneCommand is the command that clones repositories: "git" (the
+	// default) clones into the ghq layout by itself, "ghq" runs ghq get.
+	CloneCommand string `yaml:"clone_command,omitempty"`
+	// CloneRoots choose the root new clones of matching owners are placed
+	// under. The first match wins; other repositories go to the primary ghq
+	// root.
+	CloneRoots []CloneRoot `yaml:"clone_roots,omitempty"`
+	// Status shows the branch and the state of the working copy of cloned
+	// repositories in the selector.
+	Status bool `yaml:"status,omitempty"`
+	// NoOfflineDetection keeps gh-otui from switching to offline mode when
+	// the API does not answer, e.g. behind a proxy that blocks the check.
+	NoOfflineDetection bool `yaml:"no_offline_detection,omitempty"`
+	// LineFormat is the text/template of the selector lines. See
+	// models.LineFormat.
+	LineFormat string `yaml:"line_format,omitempty"`
+	// Theme colors the selector lines: one of models.Themes. The lines are
+	// plain when it is empty or NO_COLOR is set.
+	Theme string `yaml:"theme,omitempty"`
+	// Sort is the order of the selector list: one of models.SortModes.
+	// Empty means frecency.
+	Sort string `yaml:"sort,omitempty"`
+	// Pins lists the repositories, as "host/owner/name", that are always
+	// listed first.
+	Pins []string `yaml:"pins,omitempty"`
+	// Selector is the selector command, such as "peco", "fzf" or "sk".
+	// Empty means peco if it is installed, else fzf.
+	Selector string `yaml:"selector,omitempty"`
+	// CacheTTL is how old the cache may get, as a duration like "30m",
+	// before it is refreshed in the background. Empty means an hour.
+	CacheTTL string `yaml:"cache_ttl,omitempty"`
+	// CloneProtocol is the protocol of the clone URLs: "ssh" (the default)
+	// or "https".
+	CloneProtocol string `yaml:"clone_protocol,omitempty"`
+	// Actions are extra fzf key bindings that run a shell command on the
+	// repository under the cursor.
+	Actions []Action `yaml:"actions,omitempty"`
+}
+
+// Action runs Run with sh when Key is pressed in fzf. The repository is
+// passed in the GH_OTUI_REPO, GH_OTUI_HOST, GH_OTUI_OWNER, GH_OTUI_NAME,
+// GH_OTUI_URL and GH_OTUI_PATH environment variables; GH_OTUI_PATH is empty
+// when the repository is not cloned.
+type Action struct {
+	Key         string `yaml:"key"`
+	Run         string `yaml:"run"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// Action returns the action bound to key.
+func (c *Config) Action(key string) (Action, bool) {
+	for _, a := range c.Actions {
+		if a.Key == key {
+			return a, true
+		}
+	}
+	return Action{}, false
+}
+
+// reservedKeys are the fzf keys gh-otui binds itself.
+var reservedKeys = []string{"enter", "esc", "ctrl-c", "ctrl-s", "alt-p"}
+
+const DefaultCacheTTL = time.Hour
+
+func (c *Config) TTL() time.Duration {
+	if d, err := time.ParseDuration(c.CacheTTL); err == nil && d > 0 {
+		return d
+	}
+	return DefaultCacheTTL
+}
+
+const (
+	CloneWithSSH   = "ssh"
+	CloneWithHTTPS = "https"
+)
+
+func (c *Config) Protocol() string {
+	if c.CloneProtocol == "" {
+		return CloneWithSSH
+	}
+	return c.CloneProtocol
+}
+
+func (c *Config) SortMode() string {
+	if c.Sort == "" {
+		return models.SortFrecency
+	}
+	return c.Sort
+}
+
+func (c *Config) Format() (*models.LineFormat, error) {
+	f, err := models.NewLineFormat(c.LineFormat, c.Theme)
+	if err != nil {
+		return nil, fmt.Errorf("invalid line_format: %w", err)
+	}
+	return f, nil
+}
+
+// CloneRoot places the clones of owners matching Match, a glob pattern
+// matched like the orgs patterns, under Root.
+type CloneRoot struct {
+	Match string `yaml:"match"`
+	Root  string `yaml:"root"`
+}
+
+// CloneRoot returns the root the clones of repositories of host/owner are
+// placed under, or "" for the primary ghq root.
+func (c *Config) CloneRoot(host, owner string) string {
+	for _, r := range c.CloneRoots {
+		if matchAny([]string{r.Match}, owner) || matchAny([]string{r.Match}, host+"/"+owner) {
+			return expandHome(r.Root)
+		}
+	}
+	return ""
+}
+
+// CloneRootDirs returns the roots of CloneRoots.
+func (c *Config) CloneRootDirs() []string {
+	dirs := make([]string, 0, len(c.CloneRoots))
+	for _, r := range c.CloneRoots {
+		dirs = append(dirs, expandHome(r.Root))
+	}
+	return dirs
+}
+
+func expandHome(dir string) string {
+	if rest, ok := strings.CutPrefix(dir, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return filepath.Clean(dir)
+}
+
+const (
+	CloneWithGit = "git"
+	CloneWithGhq = "ghq"
+)
+
+func (c *Config) Cloner() string {
+	if c.CloneCommand == "" {
+		return CloneWithGit
+	}
+	return c.CloneCommand
+}
+
+func (c *Config) PageLimit() int {
+	if c.MaxPages > 0 {
+		return c.MaxPages
+	}
+	return github.DefaultMaxPages
+}
+
+// Patterns is a pair of allow and deny lists of glob patterns.
+// An empty allow list allows everything that is not denied.
+type Patterns struct {
+	Allow []string `yaml:"allow,omitempty"`
+	Deny  []string `yaml:"deny,omitempty"`
+	// also holds the allow lists added by Restrict, which must match too.
+	also [][]string
+}
+
+// Match reports whether any of names is allowed and none is denied.
+func (p Patterns) Match(names ...string) bool {
+	for _, name := range names {
+		if matchAny(p.Deny, name) {
+			return false
+		}
+	}
+	for _, allow := range append([][]string{p.Allow}, p.also...) {
+		if len(allow) > 0 && !slices.ContainsFunc(names, func(name string) bool {
+			return matchAny(allow, name)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Restrict returns p narrowed by other: names must also match the allow list
+// of other, and the deny list of other is added to that of p.
+func (p Patterns) Restrict(other Patterns) Patterns {
+	return Patterns{
+		Allow: slices.Clone(p.Allow),
+		Deny:  slices.Concat(p.Deny, other.Deny),
+		also:  slices.Concat(p.also, [][]string{other.Allow}, other.also),
+	}
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowHost reports whether repositories on host should be listed.
+func (c *Config) AllowHost(host string) bool {
+	return c.Hosts.Match(host)
+}
+
+// AllowOwner reports whether repositories owned by the organization or user
+// should be listed. Patterns containing a slash are matched against "host/owner".
+func (c *Config) AllowOwner(host, owner string) bool {
+	return c.Orgs.Match(owner, host+"/"+owner)
+}
+
+func (c *Config) IsExtraOwner(host, owner string) bool {
+	return slices.ContainsFunc(c.ExtraOwners[host], func(o string) bool {
+		return strings.EqualFold(o, owner)
+	})
+}
+
+// AddOwner adds owner to the extra owners of host. It reports false if the
+// owner was already configured.
+func (c *Config) AddOwner(host, owner string) bool {
+	if c.IsExtraOwner(host, owner) {
+		return false
+	}
+	if c.ExtraOwners == nil {
+		c.ExtraOwners = make(map[string][]string)
+	}
+	c.ExtraOwners[host] = append(c.ExtraOwners[host], owner)
+	return true
+}
+
+func (c *Config) IsPinned(key string) bool {
+	return slices.ContainsFunc(c.Pins, func(pin string) bool {
+		return strings.EqualFold(pin, key)
+	})
+}
+
+// Pin adds key to the pins. It returns false if it is already pinned.
+func (c *Config) Pin(key string) bool {
+	if c.IsPinned(key) {
+		return false
+	}
+	c.Pins = append(c.Pins, key)
+	return true
+}
+
+// Unpin removes key from the pins. It returns false if it is not pinned.
+func (c *Config) Unpin(key string) bool {
+	if !c.IsPinned(key) {
+		return false
+	}
+	c.Pins = slices.DeleteFunc(c.Pins, func(pin string) bool {
+		return strings.EqualFold(pin, key)
+	})
+	return true
+}
+
+func Path() string {
+	if p := os.Getenv("GH_OTUI_CONFIG"); p != "" {
+		return p
+	}
+	return filepath.Join(ghconfig.ConfigDir(), "gh-otui.yml")
+}
+
+func Load() (*Config, error) {
+	c, err := Read()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", Path(), err)
+	}
+	return c, nil
+}
+
+// Read reads the config file without validating the values, so that invalid
+// values can still be fixed with Set.
+func Read() (*Config, error) {
+	var c Config
+	b, err := os.ReadFile(Path())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &c, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config %s: %w", Path(), unknownKeys(err))
+	}
+	return &c, nil
+}
+
+// Validate checks the values of the config, naming the bad key in the error.
+func (c *Config) Validate() error {
+	for _, ch := range checks {
+		if err := ch.check(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateKey checks only the values of the named key, so that one key can be
+// fixed while others are still invalid.
+func (c *Config) validateKey(name string) error {
+	for _, ch := range checks {
+		if ch.key != name {
+			continue
+		}
+		if err := ch.check(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func patternsCheck(key string, patterns func(c *Config) []string) func(c *Config) error {
+	return func(c *Config) error {
+		for _, pattern := range patterns(c) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid %s pattern %q: %w", key, pattern, err)
+			}
+		}
+		return nil
+	}
+}
+
+// checks validate the config key by key, in the order Validate reports them.
+var checks = []struct {
+	key   string
+	check func(c *Config) error
+}{
+	{"hosts.allow", patternsCheck("hosts.allow", func(c *Config) []string { return c.Hosts.Allow })},
+	{"hosts.deny", patternsCheck("hosts.deny", func(c *Config) []string { return c.Hosts.Deny })},
+	{"orgs.allow", patternsCheck("orgs.allow", func(c *Config) []string { return c.Orgs.Allow })},
+	{"orgs.deny", patternsCheck("orgs.deny", func(c *Config) []string { return c.Orgs.Deny })},
+	{"max_pages", func(c *Config) error {
+		if c.MaxPages < 0 {
+			return fmt.Errorf("invalid max_pages %d: must not be negative", c.MaxPages)
+		}
+		return nil
+	}},
+	{"clone_roots", func(c *Config) error {
+		for _, r := range c.CloneRoots {
+			if _, err := path.Match(r.Match, ""); err != nil || r.Match == "" {
+				return fmt.Errorf("invalid clone_roots match %q", r.Match)
+			}
+			if r.Root == "" {
+				return fmt.Errorf("invalid clone_roots entry for %q: root is empty", r.Match)
+			}
+		}
+		return nil
+	}},
+	{"pins", func(c *Config) error {
+		for _, pin := range c.Pins {
+			if _, err := models.ParsePath(pin); err != nil {
+				return fmt.Errorf("invalid pins entry %q: %w", pin, err)
+			}
+		}
+		return nil
+	}},
+	{"sort", func(c *Config) error {
+		if c.Sort != "" && !slices.Contains(models.SortModes, c.Sort) {
+			return fmt.Errorf("invalid sort %q: must be one of %s", c.Sort, strings.Join(models.SortModes, ", "))
+		}
+		return nil
+	}},
+	{"theme", func(c *Config) error {
+		if _, ok := models.Themes[c.Theme]; c.Theme != "" && !ok {
+			return fmt.Errorf("invalid theme %q: must be one of %s", c.Theme, strings.Join(slices.Sorted(maps.Keys(models.Themes)), ", "))
+		}
+		return nil
+	}},
+	{"line_format", func(c *Config) error {
+		// テーマの誤りは theme で報告する
+		if _, err := models.NewLineFormat(c.LineFormat, ""); err != nil {
+			return fmt.Errorf("invalid line_format: %w", err)
+		}
+		return nil
+	}},
+	{"clone_command", func(c *Config) error {
+		if c.CloneCommand != "" && c.CloneCommand != CloneWithGit && c.CloneCommand != CloneWithGhq {
+			return fmt.Errorf("invalid clone_command %q: must be %s or %s", c.CloneCommand, CloneWithGit, CloneWithGhq)
+		}
+		return nil
+	}},
+	{"cache_ttl", func(c *Config) error {
+		if d, err := time.ParseDuration(c.CacheTTL); c.CacheTTL != "" && (err != nil || d <= 0) {
+			return fmt.Errorf("invalid cache_ttl %q: must be a positive duration such as 30m or 2h", c.CacheTTL)
+		}
+		return nil
+	}},
+	{"clone_protocol", func(c *Config) error {
+		if c.CloneProtocol != "" && c.CloneProtocol != CloneWithSSH && c.CloneProtocol != CloneWithHTTPS {
+			return fmt.Errorf("invalid clone_protocol %q: must be %s or %s", c.CloneProtocol, CloneWithSSH, CloneWithHTTPS)
+		}
+		return nil
+	}},
+	{"actions", func(c *Config) error {
+		for i, a := range c.Actions {
+			switch {
+			case a.Key == "":
+				return fmt.Errorf("invalid actions[%d]: key is empty", i)
+			case slices.Contains(reservedKeys, a.Key):
+				return fmt.Errorf("invalid actions[%d] key %q: the key is used by gh-otui", i, a.Key)
+			case a.Run == "":
+				return fmt.Errorf("invalid actions[%d] for %q: run is empty", i, a.Key)
+			}
+			if slices.ContainsFunc(c.Actions[:i], func(b Action) bool { return b.Key == a.Key }) {
+				return fmt.Errorf("invalid actions[%d]: key %q is bound twice", i, a.Key)
+			}
+		}
+		return nil
+	}},
+}
+
+var unknownField = regexp.MustCompile(`^line (\d+): field (\S+) not found in type \S+$`)
+
+// unknownKeys rewrites the errors yaml reports for keys that are not in
+// Config, e.g. typos, to name the key.
+func unknownKeys(err error) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+	msgs := make([]string, len(typeErr.Errors))
+	for i, msg := range typeErr.Errors {
+		if m := unknownField.FindStringSubmatch(msg); m != nil {
+			msg = fmt.Sprintf("unknown key %q on line %s", m[2], m[1])
+		}
+		msgs[i] = msg
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+func Save(c *Config) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(Path()), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(Path(), b, 0644); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
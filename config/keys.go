@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Key is a setting that gh otui config reads and writes. Every key can be
+// overridden by the environment variable named by EnvName, which in turn is
+// overridden by the matching flag, if any.
+type Key struct {
+	Name        string
+	Description string
+	get         func(c *Config) string
+	set         func(c *Config, v string) error
+}
+
+// EnvName returns the environment variable that overrides the key, e.g.
+// GH_OTUI_CACHE_TTL for cache_ttl.
+func (k Key) EnvName() string {
+	return "GH_OTUI_" + strings.ToUpper(strings.ReplaceAll(k.Name, ".", "_"))
+}
+
+// Keys lists the settings with a single value. Lists are written as comma
+// separated values. The structured settings, such as clone_roots and actions,
+// are only edited in the file.
+var Keys = []Key{
+	stringKey("selector", "selector command, e.g. peco or fzf", func(c *Config) *string { return &c.Selector }),
+	stringKey("cache_ttl", "age after which the cache is refreshed, e.g. 30m", func(c *Config) *string { return &c.CacheTTL }),
+	listKey("hosts.allow", "only list repositories on matching hosts", func(c *Config) *[]string { return &c.Hosts.Allow }),
+	listKey("hosts.deny", "hide repositories on matching hosts", func(c *Config) *[]string { return &c.Hosts.Deny }),
+	listKey("orgs.allow", "only list repositories of matching owners", func(c *Config) *[]string { return &c.Orgs.Allow }),
+	listKey("orgs.deny", "hide repositories of matching owners", func(c *Config) *[]string { return &c.Orgs.Deny }),
+	listKey("sources", "extra listings to fetch: starred, watching", func(c *Config) *[]string { return &c.Sources }),
+	{
+		Name:        "max_pages",
+		Description: "pages of 100 repositories fetched per listing",
+		get: func(c *Config) string {
+			if c.MaxPages == 0 {
+				return ""
+			}
+			return strconv.Itoa(c.MaxPages)
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.MaxPages = 0
+				return nil
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid max_pages %q: must be a number", v)
+			}
+			c.MaxPages = n
+			return nil
+		},
+	},
+	stringKey("clone_command", "command that clones: git or ghq", func(c *Config) *string { return &c.CloneCommand }),
+	stringKey("clone_protocol", "protocol of clone URLs: ssh or https", func(c *Config) *string { return &c.CloneProtocol }),
+	boolKey("status", "show the working copy status of clones: true or false", func(c *Config) *bool { return &c.Status }),
+	boolKey("no_offline_detection", "never switch to offline mode when the API does not answer: true or false", func(c *Config) *bool { return &c.NoOfflineDetection }),
+	stringKey("line_format", "template of the selector lines", func(c *Config) *string { return &c.LineFormat }),
+	stringKey("theme", "colors of the selector lines", func(c *Config) *string { return &c.Theme }),
+	stringKey("sort", "order of the selector list", func(c *Config) *string { return &c.Sort }),
+}
+
+func stringKey(name, description string, field func(c *Config) *string) Key {
+	return Key{
+		Name:        name,
+		Description: description,
+		get:         func(c *Config) string { return *field(c) },
+		set: func(c *Config, v string) error {
+			*field(c) = v
+			return nil
+		},
+	}
+}
+
+func boolKey(name, description string, field func(c *Config) *bool) Key {
+	return Key{
+		Name:        name,
+		Description: description,
+		get:         func(c *Config) string { return strconv.FormatBool(*field(c)) },
+		set: func(c *Config, v string) error {
+			if v == "" {
+				*field(c) = false
+				return nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: must be true or false", name, v)
+			}
+			*field(c) = b
+			return nil
+		},
+	}
+}
+
+func listKey(name, description string, field func(c *Config) *[]string) Key {
+	return Key{
+		Name:        name,
+		Description: description,
+		get:         func(c *Config) string { return strings.Join(*field(c), ",") },
+		set: func(c *Config, v string) error {
+			var values []string
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					values = append(values, s)
+				}
+			}
+			*field(c) = values
+			return nil
+		},
+	}
+}
+
+func lookupKey(name string) (Key, error) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	return Key{}, fmt.Errorf("unknown key %q", name)
+}
+
+// Get returns the value of the key named name as gh otui config shows it.
+func (c *Config) Get(name string) (string, error) {
+	k, err := lookupKey(name)
+	if err != nil {
+		return "", err
+	}
+	return k.get(c), nil
+}
+
+// Set sets the key named name to value. An empty value restores the default.
+// Only the value of the key is validated, so that a file with invalid values
+// can be fixed one key at a time.
+func (c *Config) Set(name, value string) error {
+	k, err := lookupKey(name)
+	if err != nil {
+		return err
+	}
+	next := *c
+	if err := k.set(&next, value); err != nil {
+		return err
+	}
+	if err := next.validateKey(name); err != nil {
+		return err
+	}
+	*c = next
+	return nil
+}
+
+// WithEnv returns a copy of c with the keys set in the environment
+// overridden. The copy is meant for this run only and is never saved.
+func (c *Config) WithEnv() (*Config, error) {
+	next := *c
+	for _, k := range Keys {
+		v, ok := os.LookupEnv(k.EnvName())
+		if !ok {
+			continue
+		}
+		if err := k.set(&next, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", k.EnvName(), err)
+		}
+		if err := next.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", k.EnvName(), err)
+		}
+	}
+	return &next, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/n3xem/gh-otui/config"
+)
+
+// configure reads and writes the keys of the config file like gh config:
+// "get <key>", "set <key> <value>" and "list".
+func configure(cfg *config.Config, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("gh otui config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gh otui config get <key> | set <key> <value> | list")
+		fmt.Fprintln(fs.Output(), "\nKeys:")
+		for _, k := range config.Keys {
+			fmt.Fprintf(fs.Output(), "  %-15s %s\n", k.Name, k.Description)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	switch args := fs.Args(); {
+	case len(args) == 2 && args[0] == "get":
+		v, err := cfg.Get(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, v)
+		return nil
+	case len(args) == 3 && args[0] == "set":
+		if err := cfg.Set(args[1], args[2]); err != nil {
+			return err
+		}
+		return config.Save(cfg)
+	case len(args) == 1 && args[0] == "list":
+		for _, k := range config.Keys {
+			v, _ := cfg.Get(k.Name)
+			fmt.Fprintf(stdout, "%s=%s\n", k.Name, v)
+		}
+		return nil
+	}
+	fs.Usage()
+	return fmt.Errorf("invalid config command")
+}
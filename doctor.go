@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/n3xem/gh-otui/cache"
+	"github.com/n3xem/gh-otui/cmd"
+	"github.com/n3xem/gh-otui/config"
+	"github.com/n3xem/gh-otui/github"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// doctorTimeout bounds each API check, so that an unreachable host does not
+// hang the diagnosis.
+const doctorTimeout = 10 * time.Second
+
+// checkup prints the results of the doctor checks and counts the problems.
+type checkup struct {
+	w        io.Writer
+	problems int
+	started  bool
+}
+
+func (c *checkup) section(title string) {
+	if c.started {
+		fmt.Fprintln(c.w)
+	}
+	c.started = true
+	fmt.Fprintln(c.w, title)
+}
+
+func (c *checkup) ok(format string, args ...any) {
+	fmt.Fprintf(c.w, "  ✓ %s\n", fmt.Sprintf(format, args...))
+}
+
+// warn reports something that does not keep gh-otui from working.
+func (c *checkup) warn(msg, fix string) {
+	fmt.Fprintf(c.w, "  ! %s\n", msg)
+	if fix != "" {
+		fmt.Fprintf(c.w, "    → %s\n", fix)
+	}
+}
+
+func (c *checkup) fail(msg, fix string) {
+	c.problems++
+	fmt.Fprintf(c.w, "  ✗ %s\n", msg)
+	if fix != "" {
+		fmt.Fprintf(c.w, "    → %s\n", fix)
+	}
+}
+
+// doctor checks the environment gh-otui depends on and prints how to fix what
+// is wrong. It returns an error if anything needs fixing.
+func doctor(ctx context.Context, opts *options) error {
+	c := &checkup{w: opts.stdout}
+
+	c.section("Config")
+	cfg := checkConfig(c)
+
+	c.section("Commands")
+	checkCommands(ctx, c, opts.runner, cfg)
+
+	c.section("Authentication")
+	if opts.offline {
+		c.warn("skipped while offline", "")
+	} else {
+		checkHosts(ctx, c, cfg)
+	}
+
+	c.section("Clone roots")
+	checkRoots(ctx, c, opts.runner, cfg)
+
+	c.section("Cache")
+	checkCache(ctx, c, cfg)
+
+	if c.problems > 0 {
+		return fmt.Errorf("found %d problem(s)", c.problems)
+	}
+	fmt.Fprintln(opts.stdout, "\nNo problems found.")
+	return nil
+}
+
+// checkConfig returns the config to check the rest against, or the defaults
+// if it is invalid.
+func checkConfig(c *checkup) *config.Config {
+	cfg, err := config.Load()
+	if err == nil {
+		cfg, err = cfg.WithEnv()
+	}
+	if err != nil {
+		c.fail(err.Error(), "fix the key named in the error with `gh otui config set <key> <value>` or edit "+config.Path())
+		return &config.Config{}
+	}
+	if _, statErr := os.Stat(config.Path()); statErr != nil {
+		c.ok("%s (not created, using the defaults)", config.Path())
+	} else {
+		c.ok("%s", config.Path())
+	}
+	return cfg
+}
+
+func checkCommands(ctx context.Context, c *checkup, r cmd.Runner, cfg *config.Config) {
+	check := func(name, fix string, required bool) {
+		if _, err := exec.LookPath(name); err != nil {
+			if required {
+				c.fail(name+" is not installed", fix)
+			} else {
+				c.warn(name+" is not installed", fix)
+			}
+			return
+		}
+		out, err := r.Run(ctx, cmd.Command{Name: name, Args: []string{"--version"}})
+		if err != nil {
+			c.fail(fmt.Sprintf("%s --version failed: %v", name, err), "reinstall "+name)
+			return
+		}
+		version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+		c.ok("%s: %s", name, version)
+	}
+	check("gh", "install the GitHub CLI: https://cli.github.com", true)
+	check("git", "install git: https://git-scm.com", true)
+	if cfg.Cloner() == config.CloneWithGhq {
+		check("ghq", "install ghq (https://github.com/x-motemen/ghq) or `gh otui config set clone_command git`", true)
+	} else {
+		check("ghq", "only needed when clone_command is ghq", false)
+	}
+
+	if cfg.Selector != "" {
+		check(cfg.Selector, "install it or `gh otui config set selector \"\"` to use peco or fzf", true)
+		return
+	}
+	for _, selector := range []string{"peco", "fzf"} {
+		if _, err := exec.LookPath(selector); err == nil {
+			check(selector, "", true)
+			return
+		}
+	}
+	c.fail("neither peco nor fzf is installed", "install peco (https://github.com/peco/peco) or fzf (https://github.com/junegunn/fzf)")
+}
+
+// hostCheck is the outcome of checking the API of a host.
+type hostCheck struct {
+	host   string
+	login  string
+	scopes []string
+	err    error
+}
+
+func checkHosts(ctx context.Context, c *checkup, cfg *config.Config) {
+	hosts := slices.DeleteFunc(auth.KnownHosts(), func(host string) bool {
+		return !cfg.AllowHost(host)
+	})
+	if len(hosts) == 0 {
+		c.fail("not logged in to any host", "run `gh auth login`")
+		return
+	}
+	slices.Sort(hosts)
+
+	p := pool.NewWithResults[hostCheck]()
+	for _, host := range hosts {
+		p.Go(func() hostCheck {
+			ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+			defer cancel()
+			res := hostCheck{host: host}
+			client, err := newClient(host)
+			if err != nil {
+				res.err = err
+				return res
+			}
+			if res.login, res.err = client.Login(ctx); res.err != nil {
+				return res
+			}
+			res.scopes, res.err = client.Scopes(ctx)
+			return res
+		})
+	}
+	results := p.Wait()
+	slices.SortFunc(results, func(a, b hostCheck) int {
+		return strings.Compare(a.host, b.host)
+	})
+
+	for _, res := range results {
+		if _, source := auth.TokenForHost(res.host); source == "" {
+			c.fail(res.host+": no token", fmt.Sprintf("run `gh auth login --hostname %s`", res.host))
+			continue
+		}
+		switch {
+		case res.err != nil && github.IsAuthError(res.err):
+			c.fail(res.err.Error(), fmt.Sprintf("run `gh auth login --hostname %s`", res.host))
+			continue
+		case res.err != nil:
+			c.fail(fmt.Sprintf("%s: the API is not reachable: %v", res.host, res.err), "check the network, proxy settings (HTTPS_PROXY) and that the host is up")
+			continue
+		}
+		if res.scopes == nil {
+			c.ok("%s: logged in as %s (scopes not reported by the token)", res.host, res.login)
+			continue
+		}
+		c.ok("%s: logged in as %s (scopes: %s)", res.host, res.login, strings.Join(res.scopes, ", "))
+		if missing := github.MissingScopes(res.scopes); len(missing) > 0 {
+			c.warn(fmt.Sprintf("%s: the token lacks %s, so some repositories may be missing", res.host, strings.Join(missing, ", ")),
+				fmt.Sprintf("run `gh auth refresh --hostname %s --scopes %s`", res.host, strings.Join(missing, ",")))
+		}
+	}
+}
+
+func checkRoots(ctx context.Context, c *checkup, r cmd.Runner, cfg *config.Config) {
+	if _, err := exec.LookPath("git"); err != nil && cfg.Cloner() == config.CloneWithGit {
+		c.fail("cannot find the clone roots without git", "")
+		return
+	}
+	ws, err := cmd.NewWorkspace(ctx, r, cmd.WorkspaceOptions{Command: cfg.Cloner(), ExtraRoots: cfg.CloneRootDirs()})
+	if err != nil {
+		c.fail(fmt.Sprintf("failed to get the clone roots: %v", err), "check GHQ_ROOT and `git config --get-all ghq.root`")
+		return
+	}
+	for i, root := range ws.Roots() {
+		label := root
+		if i == 0 {
+			label += " (primary)"
+		}
+		info, err := os.Stat(root)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			c.warn(label+" does not exist yet", "it is created by the first clone")
+		case err != nil:
+			c.fail(err.Error(), "")
+		case !info.IsDir():
+			c.fail(label+" is not a directory", "point ghq.root or clone_roots at a directory")
+		default:
+			c.ok("%s", label)
+		}
+	}
+}
+
+func checkCache(ctx context.Context, c *checkup, cfg *config.Config) {
+	dir := cache.Dir()
+	info, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		c.warn(dir+" does not exist yet", "run `gh otui` to fetch the repositories")
+		return
+	case err != nil:
+		c.fail(err.Error(), "")
+		return
+	case !info.IsDir():
+		c.fail(dir+" is not a directory", "remove it and run `gh otui`")
+		return
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		c.fail(dir+" is not writable: "+err.Error(), "fix the permissions with `chmod u+rwx "+dir+"`")
+	} else {
+		f.Close()
+		os.Remove(f.Name())
+		c.ok("%s is writable", dir)
+	}
+
+	n, errs := cache.Verify(ctx)
+	for _, err := range errs {
+		var fileErr *cache.FileError
+		switch {
+		case !errors.As(err, &fileErr):
+			c.fail(err.Error(), "fix the permissions of "+dir)
+		case fileErr.Host != "":
+			c.fail(err.Error(), fmt.Sprintf("run `gh otui cache clear --host %s --org %s` to fetch it again", fileErr.Host, fileErr.Org))
+		case fileErr.IsHistory():
+			c.fail(err.Error(), "run `gh otui cache clear --history` to start a new history")
+		case fileErr.IsMetadata():
+			c.fail(err.Error(), "run `gh otui cache clear --metadata` to fetch everything again")
+		default:
+			c.fail(err.Error(), "remove "+fileErr.Path)
+		}
+	}
+	if len(errs) == 0 {
+		c.ok("%d cache files are valid", n)
+	}
+
+	md, err := cache.LoadMetadata(ctx)
+	if err != nil {
+		// 壊れたファイルは Verify で報告済み
+		return
+	}
+	switch {
+	case !md.Initialized():
+		c.warn("the repositories have not been fetched yet", "run `gh otui`")
+	case md.IsStale(cfg.TTL()):
+		c.warn("the cache is older than "+cfg.TTL().String(), "it is refreshed on the next run")
+	}
+	hostErrors := md.HostErrors()
+	for _, host := range slices.Sorted(maps.Keys(hostErrors)) {
+		c.fail(fmt.Sprintf("the last refresh of %s failed: %s", host, hostErrors[host]), fmt.Sprintf("fix the authentication of %s above, then run `gh otui`", host))
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/n3xem/gh-otui/cmd"
+	"github.com/n3xem/gh-otui/config"
+	"github.com/n3xem/gh-otui/models"
+)
+
+type options struct {
+	stdout io.Writer
+	stderr io.Writer
+	// runner runs ghq and the selector.
+	runner cmd.Runner
+
+	// args holds the global flags as given, to pass them on when gh-otui
+	// runs itself.
+	args     []string
+	hosts    config.Patterns
+	orgs     config.Patterns
+	sources  []string
+	verbose  bool
+	maxPages int
+	dryRun   bool
+	status   bool
+	sort     string
+	selector string
+	// offline never calls the API. It is also set when the API cannot be
+	// reached.
+	offline bool
+	// reachChecked is set once detectOffline has checked the API, so that
+	// the check runs at most once per run.
+	reachChecked bool
+}
+
+type stringsFlag struct {
+	values *[]string
+}
+
+func (f stringsFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ",")
+}
+
+func (f stringsFlag) Set(v string) error {
+	*f.values = append(*f.values, v)
+	return nil
+}
+
+// parseArgs parses the global flags and returns the remaining subcommand arguments.
+func parseArgs(args []string, stdout, stderr io.Writer) (*options, []string, error) {
+	opts := options{stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("gh otui", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gh otui [flags] [clear|doctor|add-owner|search|recent|pin|unpin|config|cache]")
+		fs.PrintDefaults()
+	}
+	fs.Var(stringsFlag{&opts.hosts.Allow}, "host", "only list repositories on hosts matching `pattern` (repeatable)")
+	fs.Var(stringsFlag{&opts.hosts.Deny}, "exclude-host", "hide repositories on hosts matching `pattern` (repeatable)")
+	fs.Var(stringsFlag{&opts.orgs.Allow}, "org", "only list repositories of owners matching `pattern` (repeatable)")
+	fs.Var(stringsFlag{&opts.orgs.Deny}, "exclude-org", "hide repositories of owners matching `pattern` (repeatable)")
+	fs.Var(stringsFlag{&opts.sources}, "source", "only list repositories found through `source`: starred or watching (repeatable)")
+	fs.IntVar(&opts.maxPages, "max-pages", 0, "fetch at most `n` pages of 100 repositories per listing (default from config, or 100)")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "print the commands that would clone repositories instead of running them")
+	fs.BoolVar(&opts.status, "status", false, "show the branch and the state of the working copy of cloned repositories")
+	fs.StringVar(&opts.sort, "sort", "", "list repositories by `mode`: "+strings.Join(models.SortModes, ", ")+" (default from config, or frecency)")
+	fs.StringVar(&opts.selector, "selector", "", "use `command` as the selector, e.g. peco or fzf (default from $GH_OTUI_SELECTOR or config)")
+	fs.BoolVar(&opts.offline, "offline", false, "never call the API: list only cached and local repositories and do not clone")
+	fs.BoolVar(&opts.verbose, "verbose", false, "report API usage and other details to stderr")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if opts.maxPages < 0 {
+		return nil, nil, fmt.Errorf("invalid -max-pages %d: must not be negative", opts.maxPages)
+	}
+	if opts.sort != "" && !slices.Contains(models.SortModes, opts.sort) {
+		return nil, nil, fmt.Errorf("invalid -sort %q: must be one of %s", opts.sort, strings.Join(models.SortModes, ", "))
+	}
+	opts.args = args[:len(args)-fs.NArg()]
+	opts.runner = cmd.ExecRunner{}
+	if opts.dryRun {
+		opts.runner = cmd.DryRunRunner{Out: stderr, Next: opts.runner}
+	}
+	return &opts, fs.Args(), nil
+}
+
+// filter returns cfg narrowed by the flag filters: repositories must pass
+// both the configured and the flag filters.
+func (o *options) filter(cfg *config.Config) *config.Config {
+	c := *cfg
+	c.Hosts = cfg.Hosts.Restrict(o.hosts)
+	c.Orgs = cfg.Orgs.Restrict(o.orgs)
+	return &c
+}
+
+// log returns where verbose output goes.
+func (o *options) log() io.Writer {
+	if o.verbose {
+		return o.stderr
+	}
+	return io.Discard
+}
+
+func (o *options) allowSource(repo models.Repository) bool {
+	if len(o.sources) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(o.sources, repo.HasSource)
+}
@@ -2,17 +2,19 @@ package github
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"iter"
 	"maps"
+	"net/http"
 	"net/url"
-	"strconv"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/n3xem/gh-otui/models"
-	"github.com/sourcegraph/conc/pool"
 )
 
 type Organization struct {
@@ -20,102 +22,92 @@ type Organization struct {
 }
 
 type Repository struct {
-	Name    string `json:"name"`
-	HtmlUrl string `json:"html_url"`
-	OrgName string
-	Host    string
+	Name        string    `json:"name"`
+	HtmlUrl     string    `json:"html_url"`
+	Description string    `json:"description"`
+	Language    string    `json:"language"`
+	Stars       int       `json:"stargazers_count"`
+	PushedAt    time.Time `json:"pushed_at"`
+	Private     bool      `json:"private"`
+	Archived    bool      `json:"archived"`
+	OrgName     string
+	Host        string
 }
 
 func (r Repository) ToDomain() models.Repository {
 	return models.Repository{
-		Name:    r.Name,
-		OrgName: r.OrgName,
-		Host:    r.Host,
-		HtmlUrl: r.HtmlUrl,
+		Name:        r.Name,
+		OrgName:     r.OrgName,
+		Host:        r.Host,
+		HtmlUrl:     r.HtmlUrl,
+		Description: r.Description,
+		Language:    r.Language,
+		Stars:       r.Stars,
+		PushedAt:    r.PushedAt,
+		Private:     r.Private,
+		Archived:    r.Archived,
 	}
 }
 
 type Client struct {
-	client *api.RESTClient
-	host   string
+	client    *api.RESTClient
+	host      string
+	transport *rateLimitTransport
+	maxPages  int
+
+	mu        sync.Mutex
+	truncated []Truncation
+
+	loginMu sync.Mutex
+	login   string
 }
 
-func (c *Client) fetchOrgRepositories(ctx context.Context, org string, page int) (repos []Repository, nextPage int, lastPage int, err error) {
-	resp, err := c.client.RequestWithContext(ctx, "GET", fmt.Sprintf("orgs/%s/repos?per_page=100&page=%d", org, page), nil)
-	if err != nil {
-		return nil, 0, 0, fmt.Errorf("failed to fetch organization repositories for %s: %w", org, err)
-	}
-	defer resp.Body.Close()
-	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-		return nil, 0, 0, fmt.Errorf("failed to unmarshal organization repositories for %s: %w", org, err)
-	}
+// DefaultMaxPages is the default number of pages of 100 repositories fetched
+// from a single listing.
+const DefaultMaxPages = 100
 
-	// Linkヘッダーの処理
-	linkHeader := resp.Header.Get("Link")
-	if linkHeader != "" {
-		next, last, err := parseLinkHeader(linkHeader)
-		if err != nil {
-			return nil, 0, 0, fmt.Errorf("failed to parse link header: %w", err)
-		}
-		nextPage = next
-		lastPage = last
-	}
+// Truncation records a listing that had more pages than were fetched.
+type Truncation struct {
+	Host     string
+	Listing  string
+	Fetched  int
+	LastPage int
+}
 
-	for i := range repos {
-		repos[i].OrgName = org
-		hostWithPath := strings.TrimPrefix(repos[i].HtmlUrl, "https://")
-		repos[i].Host = strings.Split(hostWithPath, "/")[0]
+func (t Truncation) String() string {
+	return fmt.Sprintf("%s/%s has %d pages but only the first %d were fetched", t.Host, t.Listing, t.LastPage, t.Fetched)
+}
+
+func FetchCollaboratingRepositories(ctx context.Context, client *Client) (iter.Seq[*models.RepositoryGroup], error) {
+	ghRepos, err := client.paginate(ctx, affiliationCollaborator.listing())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch collaborating repositories: %w", err)
 	}
-	return repos, nextPage, lastPage, nil
+	return groupByOwner(mapValues(ghRepos, Repository.ToDomain), models.NewRepositoryGroup)
 }
 
-func parseLinkHeader(linkHeader string) (nextPage, lastPage int, err error) {
-	links := strings.Split(linkHeader, ",")
-	for _, link := range links {
-		if strings.Contains(link, `rel="next"`) {
-			parts := strings.Split(link, ";")
-			urlPart := strings.Trim(parts[0], " <>")
-			parsedURL, err := url.Parse(urlPart)
-			if err != nil {
-				return 0, 0, err
-			}
-			query := parsedURL.Query()
-			if pageStr := query.Get("page"); pageStr != "" {
-				nextPage, err = strconv.Atoi(pageStr)
-				if err != nil {
-					return 0, 0, err
-				}
-			}
-		} else if strings.Contains(link, `rel="last"`) {
-			parts := strings.Split(link, ";")
-			urlPart := strings.Trim(parts[0], " <>")
-			parsedURL, err := url.Parse(urlPart)
-			if err != nil {
-				return 0, 0, err
-			}
-			query := parsedURL.Query()
-			if pageStr := query.Get("page"); pageStr != "" {
-				lastPage, err = strconv.Atoi(pageStr)
-				if err != nil {
-					return 0, 0, err
-				}
-			}
-		}
+// FetchSourceRepositories fetches the login user's repositories listed by
+// source, grouped by owner.
+func FetchSourceRepositories(ctx context.Context, client *Client, source Source) (iter.Seq[*models.RepositoryGroup], error) {
+	path, err := source.path()
+	if err != nil {
+		return nil, err
+	}
+	ghRepos, err := client.paginate(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s repositories: %w", source, err)
 	}
-	return nextPage, lastPage, nil
+	return groupByOwner(mapValues(ghRepos, Repository.ToDomain), func(repos ...models.Repository) (*models.RepositoryGroup, error) {
+		return models.NewSourceRepositoryGroup(string(source), repos...)
+	})
 }
 
-func FetchCollaboratingRepositories(ctx context.Context, client *Client) (iter.Seq[*models.RepositoryGroup], error) {
+func groupByOwner(repos []models.Repository, newGroup func(...models.Repository) (*models.RepositoryGroup, error)) (iter.Seq[*models.RepositoryGroup], error) {
 	type key struct {
 		host string
 		org  string
 	}
 	groups := make(map[key]*models.RepositoryGroup)
-	ghRepos, err := fetchCollaboratingRepositories(ctx, client)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch collaborating repositories: %w", err)
-	}
-	repos := mapValues(ghRepos, Repository.ToDomain)
 	for _, repo := range repos {
 		key := key{
 			host: repo.Host,
@@ -127,7 +119,7 @@ func FetchCollaboratingRepositories(ctx context.Context, client *Client) (iter.S
 				return nil, fmt.Errorf("failed to add repository to group: %w", err)
 			}
 		} else {
-			group, err := models.NewRepositoryGroup(repo)
+			group, err := newGroup(repo)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create repository group: %w", err)
 			}
@@ -137,87 +129,29 @@ func FetchCollaboratingRepositories(ctx context.Context, client *Client) (iter.S
 	return maps.Values(groups), nil
 }
 
-func fetchCollaboratingRepositories(ctx context.Context, client *Client) ([]Repository, error) {
-	maxAttempts := 100
-	allRepos := make([]Repository, 0, 10000)
-	repos, nextPage, lastPage, err := client.fetchUserRepositories(ctx, affiliationCollaborator, 1)
-	if err != nil {
-		return nil, err
-	}
-	allRepos = append(allRepos, repos...)
-	if lastPage == 0 || lastPage == 1 {
-		return allRepos, nil
-	}
-	lastPage = min(lastPage, maxAttempts)
-	p := pool.NewWithResults[[]Repository]().WithContext(ctx).WithMaxGoroutines(5)
-	for page := nextPage; page <= lastPage; page++ {
-		p.Go(func(ctx context.Context) ([]Repository, error) {
-			repos, _, _, err := client.fetchUserRepositories(ctx, affiliationCollaborator, page)
-			if err != nil {
-				return nil, err
-			}
-			return repos, nil
-		})
-	}
-	repoLists, err := p.Wait()
-	if err != nil {
-		return nil, err
-	}
-	allRepos = append(allRepos, flatten(repoLists)...)
-	return allRepos, nil
-}
-
+// FetchUserRepositories fetches the repositories owned by the login user.
+// A user without repositories gets an empty group.
 func FetchUserRepositories(ctx context.Context, client *Client) (*models.RepositoryGroup, error) {
-	ghRepos, err := fetchUserRepositories(ctx, client)
+	ghRepos, err := client.paginate(ctx, affiliationOwner.listing())
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch repositories for user: %w", err)
 	}
 
-	repos := mapValues(ghRepos, Repository.ToDomain)
-
-	g, err := models.NewRepositoryGroup(repos...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create repository group: %w", err)
-	}
-	return g, nil
-}
-
-func fetchUserRepositories(ctx context.Context, client *Client) ([]Repository, error) {
-	maxAttempts := 100
-	allRepos := make([]Repository, 0, 10000)
-
-	repos, nextPage, lastPage, err := client.fetchUserRepositories(ctx, affiliationOwner, 1)
+	login, err := client.Login(ctx)
 	if err != nil {
 		return nil, err
 	}
-	allRepos = append(allRepos, repos...)
-	if lastPage == 0 || lastPage == 1 {
-		return repos, nil
-	}
-	lastPage = min(lastPage, maxAttempts)
-
-	p := pool.NewWithResults[[]Repository]().WithContext(ctx).WithMaxGoroutines(5)
-	for page := nextPage; page <= lastPage; page++ {
-		p.Go(func(ctx context.Context) ([]Repository, error) {
-			repos, _, _, err := client.fetchUserRepositories(ctx, affiliationOwner, page)
-			if err != nil {
-				return nil, err
-			}
-			return repos, nil
-		})
-	}
-
-	repoLists, err := p.Wait()
+	g, err := models.NewOwnerRepositoryGroup(client.host, login, mapValues(ghRepos, Repository.ToDomain)...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create repository group: %w", err)
 	}
-	allRepos = append(allRepos, flatten(repoLists)...)
-	return allRepos, nil
+	return g, nil
 }
 
 type OwnerOrganization struct {
 	name   string
 	client *Client
+	user   bool
 }
 
 func NewOrganizations(ctx context.Context, client *Client) ([]*OwnerOrganization, error) {
@@ -246,37 +180,35 @@ func NewOrganization(orgName string, client *Client) (*OwnerOrganization, error)
 	}, nil
 }
 
-func (o *OwnerOrganization) fetchRepositories(ctx context.Context) ([]Repository, error) {
-	maxAttempts := 100 // 安全のための最大ページ数
-	allRepos := make([]Repository, 0, 10000)
-
-	repos, nextPage, lastPage, err := o.client.fetchOrgRepositories(ctx, o.name, 1)
-	if err != nil {
-		return nil, err
+// NewOwner looks up an arbitrary organization or user, which the login user
+// does not need to be a member of.
+func NewOwner(ctx context.Context, name string, client *Client) (*OwnerOrganization, error) {
+	if name == "" {
+		return nil, fmt.Errorf("owner name cannot be empty")
 	}
-	allRepos = append(allRepos, repos...)
-	if lastPage == 0 || lastPage == 1 {
-		return allRepos, nil
+	var owner struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
 	}
-	lastPage = min(lastPage, maxAttempts)
-
-	p := pool.NewWithResults[[]Repository]().WithContext(ctx).WithMaxGoroutines(5)
-	for page := nextPage; page <= lastPage; page++ {
-		p.Go(func(ctx context.Context) ([]Repository, error) {
-			repos, _, _, err := o.client.fetchOrgRepositories(ctx, o.name, page)
-			if err != nil {
-				return nil, err
-			}
-			return repos, nil
-		})
+	if err := client.client.DoWithContext(ctx, "GET", "users/"+url.PathEscape(name), nil, &owner); err != nil {
+		return nil, fmt.Errorf("failed to look up owner %s on %s: %w", name, client.host, err)
 	}
+	return &OwnerOrganization{
+		name:   owner.Login,
+		client: client,
+		user:   owner.Type != "Organization",
+	}, nil
+}
 
-	repoLists, err := p.Wait()
-	if err != nil {
-		return nil, err
+func (o *OwnerOrganization) Name() string {
+	return o.name
+}
+
+func (o *OwnerOrganization) reposPath() string {
+	if o.user {
+		return fmt.Sprintf("users/%s/repos", o.name)
 	}
-	allRepos = append(allRepos, flatten(repoLists)...)
-	return allRepos, nil
+	return fmt.Sprintf("orgs/%s/repos", o.name)
 }
 
 func flatten[T any](slices [][]T) []T {
@@ -299,15 +231,15 @@ func mapValues[U any, S ~[]U, V any](values S, f func(U) V) []V {
 	return results
 }
 
+// FetchRepositories fetches every repository of the organization or user.
+// An owner without repositories gets an empty group.
 func (o *OwnerOrganization) FetchRepositories(ctx context.Context) (*models.RepositoryGroup, error) {
-	ghRepos, err := o.fetchRepositories(ctx)
+	ghRepos, err := o.client.paginate(ctx, o.reposPath())
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch repositories for organization %s: %w", o.name, err)
 	}
 
-	repos := mapValues(ghRepos, Repository.ToDomain)
-
-	g, err := models.NewRepositoryGroup(repos...)
+	g, err := models.NewOwnerRepositoryGroup(o.client.host, o.name, mapValues(ghRepos, Repository.ToDomain)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create repository group: %w", err)
 	}
@@ -315,12 +247,122 @@ func (o *OwnerOrganization) FetchRepositories(ctx context.Context) (*models.Repo
 }
 
 func NewClient(opts api.ClientOptions) (*Client, error) {
+	transport := newRateLimitTransport(opts.Transport)
+	opts.Transport = transport
 	client, err := api.NewRESTClient(opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize client for %s: %w", opts.Host, err)
 	}
 
-	return &Client{client: client, host: opts.Host}, nil
+	return &Client{client: client, host: opts.Host, transport: transport, maxPages: DefaultMaxPages}, nil
+}
+
+// SetMaxPages sets the number of pages fetched from a single listing.
+// Listings with more pages are truncated and reported by Truncations.
+func (c *Client) SetMaxPages(n int) {
+	c.maxPages = n
+}
+
+// Truncations returns the listings that were cut off at the page limit.
+func (c *Client) Truncations() []Truncation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.truncated)
+}
+
+// capPages limits lastPage to the page limit and records the truncation of
+// listing if it exceeds it.
+func (c *Client) capPages(listing string, lastPage int) int {
+	if c.maxPages <= 0 || lastPage <= c.maxPages {
+		return lastPage
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.truncated = append(c.truncated, Truncation{
+		Host:     c.host,
+		Listing:  listing,
+		Fetched:  c.maxPages,
+		LastPage: lastPage,
+	})
+	return c.maxPages
+}
+
+func (c *Client) Host() string {
+	return c.host
+}
+
+// RateLimit returns the rate limit reported by the last response from the host.
+func (c *Client) RateLimit() RateLimit {
+	return c.transport.RateLimit()
+}
+
+// VerifyAuth checks that the token for the host is still accepted by the API.
+func (c *Client) VerifyAuth(ctx context.Context) error {
+	_, err := c.Login(ctx)
+	return err
+}
+
+// Login returns the login of the authenticated user. It is fetched once per client.
+func (c *Client) Login(ctx context.Context) (string, error) {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+	if c.login != "" {
+		return c.login, nil
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := c.client.DoWithContext(ctx, "GET", "user", nil, &user); err != nil {
+		return "", fmt.Errorf("failed to authenticate to %s: %w", c.host, err)
+	}
+	c.login = user.Login
+	return c.login, nil
+}
+
+// Scopes returns the OAuth scopes granted to the token, or nil when the token
+// does not report them, as fine-grained tokens do not.
+func (c *Client) Scopes(ctx context.Context) ([]string, error) {
+	resp, err := c.client.RequestWithContext(ctx, "GET", "user", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate to %s: %w", c.host, err)
+	}
+	defer resp.Body.Close()
+	var scopes []string
+	for _, s := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, nil
+}
+
+// RequiredScopes are the OAuth scopes needed to list private repositories
+// and the organizations of the user.
+var RequiredScopes = []string{"repo", "read:org"}
+
+// MissingScopes returns the RequiredScopes that scopes do not grant.
+func MissingScopes(scopes []string) []string {
+	var missing []string
+	for _, required := range RequiredScopes {
+		granted := slices.Contains(scopes, required)
+		// admin:org と write:org は read:org を含む
+		if required == "read:org" {
+			granted = granted || slices.Contains(scopes, "write:org") || slices.Contains(scopes, "admin:org")
+		}
+		if !granted {
+			missing = append(missing, required)
+		}
+	}
+	return missing
+}
+
+// IsAuthError reports whether err was caused by a missing, expired or revoked token.
+func IsAuthError(err error) bool {
+	var httpErr *api.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusUnauthorized
+	}
+	return strings.Contains(err.Error(), "authentication token not found")
 }
 
 func (c *Client) FetchOrganizations(ctx context.Context) ([]Organization, error) {
@@ -338,36 +380,50 @@ const (
 	affiliationCollaborator affiliation = "collaborator"
 )
 
-// fetch login user's repositories
-func (c *Client) fetchUserRepositories(ctx context.Context, a affiliation, page int) (repos []Repository, nextPage int, lastPage int, err error) {
-	resp, err := c.client.RequestWithContext(ctx, "GET", fmt.Sprintf("user/repos?per_page=100&page=%d&affiliation=%s", page, a), nil)
-	if err != nil {
-		return nil, 0, 0, fmt.Errorf("failed to fetch user repositories: %w", err)
-	}
-	defer resp.Body.Close()
+func (a affiliation) listing() string {
+	return "user/repos?affiliation=" + string(a)
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-		return nil, 0, 0, fmt.Errorf("failed to unmarshal user repositories: %w", err)
-	}
+const searchLimit = 50
 
-	// Linkヘッダーの処理
-	linkHeader := resp.Header.Get("Link")
-	if linkHeader != "" {
-		next, last, err := parseLinkHeader(linkHeader)
-		if err != nil {
-			return nil, 0, 0, fmt.Errorf("failed to parse link header: %w", err)
-		}
-		nextPage = next
-		lastPage = last
-	}
+// SourceSearch tags repositories found by SearchRepositories.
+const SourceSearch = "search"
 
-	// リポジトリ情報の補完
+// SearchRepositories returns the best matches for query on the client's host,
+// tagged with the "search" source.
+func SearchRepositories(ctx context.Context, client *Client, query string) ([]models.Repository, error) {
+	var result struct {
+		Items []Repository `json:"items"`
+	}
+	path := fmt.Sprintf("search/repositories?q=%s&per_page=%d", url.QueryEscape(query), searchLimit)
+	if err := client.client.DoWithContext(ctx, "GET", path, nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to search repositories on %s: %w", client.host, err)
+	}
+	completeOwners(result.Items)
+	repos := mapValues(result.Items, Repository.ToDomain)
 	for i := range repos {
-		hostWithPath := strings.TrimPrefix(repos[i].HtmlUrl, "https://")
-		repos[i].Host = strings.Split(hostWithPath, "/")[0]
-		// user/reposの場合、ownerがリポジトリのオーナー
-		repos[i].OrgName = strings.Split(strings.TrimPrefix(repos[i].HtmlUrl, "https://"+repos[i].Host+"/"), "/")[0]
+		repos[i].Sources = []string{SourceSearch}
 	}
+	return repos, nil
+}
+
+// Source is a listing of repositories of the login user other than the
+// repositories they own or collaborate on.
+type Source string
 
-	return repos, nextPage, lastPage, nil
+const (
+	SourceStarred  Source = "starred"
+	SourceWatching Source = "watching"
+)
+
+var Sources = []Source{SourceStarred, SourceWatching}
+
+func (s Source) path() (string, error) {
+	switch s {
+	case SourceStarred:
+		return "user/starred", nil
+	case SourceWatching:
+		return "user/subscriptions", nil
+	}
+	return "", fmt.Errorf("unknown repository source %q", s)
 }
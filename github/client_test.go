@@ -0,0 +1,171 @@
+package github
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/n3xem/gh-otui/internal/fakegithub"
+	"github.com/n3xem/gh-otui/models"
+)
+
+func newTestClient(t *testing.T) (*Client, *fakegithub.Server) {
+	t.Helper()
+	srv := fakegithub.New("github.com")
+	t.Cleanup(srv.Close)
+	client, err := NewClient(api.ClientOptions{
+		Host:         srv.Host(),
+		AuthToken:    "token",
+		Transport:    fakegithub.Transport(srv),
+		LogIgnoreEnv: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, srv
+}
+
+func names(repos []models.Repository) []string {
+	names := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		names = append(names, repo.OrgName+"/"+repo.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func fakeNames(repos []fakegithub.Repository) []string {
+	names := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		names = append(names, repo.FullName)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestOrganizationFetchRepositories(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+	}{
+		{name: "single page", count: 30},
+		{name: "exactly one full page", count: 100},
+		{name: "multiple pages", count: 250},
+		{name: "empty", count: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, srv := newTestClient(t)
+			repos := srv.Repositories("acme", tt.count)
+			srv.AddOrganization("acme", repos...)
+
+			org, err := NewOrganization("acme", client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g, err := org.FetchRepositories(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.Host() != "github.com" || g.Organization() != "acme" {
+				t.Errorf("group = %s/%s, want github.com/acme", g.Host(), g.Organization())
+			}
+			if got, want := names(g.Repositories()), fakeNames(repos); !slices.Equal(got, want) {
+				t.Errorf("got %d repositories, want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestFetchUserRepositories(t *testing.T) {
+	t.Run("multiple pages", func(t *testing.T) {
+		client, srv := newTestClient(t)
+		srv.SetListing("user/repos?affiliation=owner", srv.Repositories("octocat", 150)...)
+		g, err := FetchUserRepositories(context.Background(), client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(g.Repositories()); got != 150 {
+			t.Errorf("got %d repositories, want 150", got)
+		}
+	})
+
+	t.Run("no repositories", func(t *testing.T) {
+		client, srv := newTestClient(t)
+		srv.SetListing("user/repos?affiliation=owner")
+		g, err := FetchUserRepositories(context.Background(), client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Organization() != "octocat" || len(g.Repositories()) != 0 {
+			t.Errorf("got group %s with %d repositories, want empty group octocat", g.Organization(), len(g.Repositories()))
+		}
+	})
+}
+
+func TestFetchCollaboratingRepositories(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.SetListing("user/repos?affiliation=collaborator", append(srv.Repositories("acme", 120), srv.Repositories("hubot", 3)...)...)
+	gs, err := FetchCollaboratingRepositories(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for g := range gs {
+		counts[g.Organization()] = len(g.Repositories())
+	}
+	if counts["acme"] != 120 || counts["hubot"] != 3 || len(counts) != 2 {
+		t.Errorf("got groups %v, want acme:120 hubot:3", counts)
+	}
+}
+
+func TestPaginateErrors(t *testing.T) {
+	t.Run("first page", func(t *testing.T) {
+		client, _ := newTestClient(t)
+		if _, err := client.paginate(context.Background(), "orgs/missing/repos"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("later page", func(t *testing.T) {
+		client, srv := newTestClient(t)
+		srv.AddOrganization("acme", srv.Repositories("acme", 350)...)
+		srv.FailPage("orgs/acme/repos", 3)
+		if _, err := client.paginate(context.Background(), "orgs/acme/repos"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestPaginateTruncation(t *testing.T) {
+	client, srv := newTestClient(t)
+	srv.AddOrganization("acme", srv.Repositories("acme", 350)...)
+	client.SetMaxPages(2)
+	repos, err := client.paginate(context.Background(), "orgs/acme/repos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 200 {
+		t.Errorf("got %d repositories, want 200", len(repos))
+	}
+	want := []Truncation{{Host: "github.com", Listing: "orgs/acme/repos", Fetched: 2, LastPage: 4}}
+	if got := client.Truncations(); !slices.Equal(got, want) {
+		t.Errorf("Truncations() = %v, want %v", got, want)
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	for _, tt := range []struct {
+		scopes []string
+		want   []string
+	}{
+		{[]string{"repo", "read:org"}, nil},
+		{[]string{"repo", "admin:org", "gist"}, nil},
+		{[]string{"public_repo"}, []string{"repo", "read:org"}},
+	} {
+		if got := MissingScopes(tt.scopes); !slices.Equal(got, tt.want) {
+			t.Errorf("MissingScopes(%q) = %q, want %q", tt.scopes, got, tt.want)
+		}
+	}
+}
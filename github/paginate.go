@@ -0,0 +1,117 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sourcegraph/conc/pool"
+)
+
+const perPage = 100
+
+// paginate fetches every page of a repository listing such as "orgs/{org}/repos".
+// The first page tells how many pages there are; the rest are fetched in
+// parallel, up to the client's page limit. An empty listing yields no
+// repositories and no error.
+func (c *Client) paginate(ctx context.Context, listing string) ([]Repository, error) {
+	repos, lastPage, err := c.fetchRepositoryPage(ctx, listing, 1)
+	if err != nil {
+		return nil, err
+	}
+	if lastPage <= 1 {
+		return repos, nil
+	}
+	lastPage = c.capPages(listing, lastPage)
+
+	p := pool.NewWithResults[[]Repository]().WithContext(ctx).WithMaxGoroutines(5)
+	for page := 2; page <= lastPage; page++ {
+		p.Go(func(ctx context.Context) ([]Repository, error) {
+			repos, _, err := c.fetchRepositoryPage(ctx, listing, page)
+			return repos, err
+		})
+	}
+	repoLists, err := p.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return append(repos, flatten(repoLists)...), nil
+}
+
+// fetchRepositoryPage fetches one page of listing and returns the number of
+// its last page, or 0 if the listing has only one page.
+func (c *Client) fetchRepositoryPage(ctx context.Context, listing string, page int) (repos []Repository, lastPage int, err error) {
+	sep := "?"
+	if strings.Contains(listing, "?") {
+		sep = "&"
+	}
+	resp, err := c.client.RequestWithContext(ctx, "GET", fmt.Sprintf("%s%sper_page=%d&page=%d", listing, sep, perPage, page), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch %s: %w", listing, err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal %s: %w", listing, err)
+	}
+
+	// Linkヘッダーの処理
+	if linkHeader := resp.Header.Get("Link"); linkHeader != "" {
+		_, lastPage, err = parseLinkHeader(linkHeader)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to parse link header: %w", err)
+		}
+	}
+
+	completeOwners(repos)
+	return repos, lastPage, nil
+}
+
+func parseLinkHeader(linkHeader string) (nextPage, lastPage int, err error) {
+	links := strings.Split(linkHeader, ",")
+	for _, link := range links {
+		if strings.Contains(link, `rel="next"`) {
+			parts := strings.Split(link, ";")
+			urlPart := strings.Trim(parts[0], " <>")
+			parsedURL, err := url.Parse(urlPart)
+			if err != nil {
+				return 0, 0, err
+			}
+			query := parsedURL.Query()
+			if pageStr := query.Get("page"); pageStr != "" {
+				nextPage, err = strconv.Atoi(pageStr)
+				if err != nil {
+					return 0, 0, err
+				}
+			}
+		} else if strings.Contains(link, `rel="last"`) {
+			parts := strings.Split(link, ";")
+			urlPart := strings.Trim(parts[0], " <>")
+			parsedURL, err := url.Parse(urlPart)
+			if err != nil {
+				return 0, 0, err
+			}
+			query := parsedURL.Query()
+			if pageStr := query.Get("page"); pageStr != "" {
+				lastPage, err = strconv.Atoi(pageStr)
+				if err != nil {
+					return 0, 0, err
+				}
+			}
+		}
+	}
+	return nextPage, lastPage, nil
+}
+
+// completeOwners fills in the host and owner of each repository from its URL.
+func completeOwners(repos []Repository) {
+	for i := range repos {
+		hostWithPath := strings.TrimPrefix(repos[i].HtmlUrl, "https://")
+		repos[i].Host = strings.Split(hostWithPath, "/")[0]
+		// user/reposの場合、ownerがリポジトリのオーナー
+		repos[i].OrgName = strings.Split(strings.TrimPrefix(repos[i].HtmlUrl, "https://"+repos[i].Host+"/"), "/")[0]
+	}
+}
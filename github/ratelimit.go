@@ -0,0 +1,227 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	maxConcurrency = 8
+	maxRetries     = 3
+	// maxRateLimitWait is the longest a request waits for the rate limit to
+	// reset before giving up.
+	maxRateLimitWait = time.Minute
+	// secondaryRateLimitWait is used when a secondary rate limit response
+	// does not say how long to wait.
+	secondaryRateLimitWait = 30 * time.Second
+)
+
+// RateLimit is the primary rate limit of a host as last reported by the API.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+func (r RateLimit) Known() bool {
+	return r.Limit > 0
+}
+
+func (r RateLimit) String() string {
+	if !r.Known() {
+		return "rate limit unknown"
+	}
+	return fmt.Sprintf("%d/%d API requests remaining, resets at %s", r.Remaining, r.Limit, r.Reset.Format(time.Kitchen))
+}
+
+// rateLimitTransport limits the number of requests in flight according to the
+// remaining rate limit budget, waits out rate limit responses and retries
+// transient server errors.
+type rateLimitTransport struct {
+	base http.RoundTripper
+
+	mu        sync.Mutex
+	rateLimit RateLimit
+	inflight  int
+	released  chan struct{}
+}
+
+func newRateLimitTransport(base http.RoundTripper) *rateLimitTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &rateLimitTransport{
+		base:     base,
+		released: make(chan struct{}),
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (t *rateLimitTransport) RateLimit() RateLimit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rateLimit
+}
+
+// concurrency returns how many requests may be in flight. It shrinks as the
+// remaining budget does so that a large refresh does not exhaust it at once.
+func (t *rateLimitTransport) concurrency() int {
+	r := t.rateLimit
+	switch {
+	case !r.Known():
+		return maxConcurrency
+	case r.Remaining*20 < r.Limit:
+		return 1
+	case r.Remaining*4 < r.Limit:
+		return 2
+	case r.Remaining*2 < r.Limit:
+		return maxConcurrency / 2
+	}
+	return maxConcurrency
+}
+
+func (t *rateLimitTransport) acquire(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		if t.inflight < t.concurrency() {
+			t.inflight++
+			t.mu.Unlock()
+			return nil
+		}
+		released := t.released
+		t.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+func (t *rateLimitTransport) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inflight--
+	close(t.released)
+	t.released = make(chan struct{})
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if err := t.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer t.release()
+
+	for attempt := 0; ; attempt++ {
+		if err := t.waitForReset(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		t.update(resp.Header)
+
+		wait, retry := retryAfter(resp, attempt)
+		if !retry || attempt >= maxRetries || req.Body != nil {
+			return resp, nil
+		}
+		if wait > maxRateLimitWait {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitForReset blocks while the primary rate limit is exhausted.
+func (t *rateLimitTransport) waitForReset(ctx context.Context) error {
+	r := t.RateLimit()
+	if !r.Known() || r.Remaining > 0 {
+		return nil
+	}
+	wait := time.Until(r.Reset)
+	if wait <= 0 {
+		return nil
+	}
+	if wait > maxRateLimitWait {
+		return fmt.Errorf("API rate limit exceeded until %s", r.Reset.Format(time.Kitchen))
+	}
+	return sleep(ctx, wait)
+}
+
+func (t *rateLimitTransport) update(h http.Header) {
+	limit, err1 := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	remaining, err2 := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	reset, err3 := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rateLimit = RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}
+}
+
+// retryAfter reports whether resp should be retried and how long to wait first.
+func retryAfter(resp *http.Response, attempt int) (time.Duration, bool) {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && isRateLimited(resp.Header):
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return d, true
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				return max(time.Until(time.Unix(reset, 0)), 0), true
+			}
+		}
+		return secondaryRateLimitWait, true
+	case resp.StatusCode == http.StatusInternalServerError,
+		resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable,
+		resp.StatusCode == http.StatusGatewayTimeout:
+		return time.Second << attempt, true
+	}
+	return 0, false
+}
+
+// isRateLimited tells rate limit 403 responses from permission errors.
+func isRateLimited(h http.Header) bool {
+	return h.Get("Retry-After") != "" || h.Get("X-RateLimit-Remaining") == "0"
+}
+
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
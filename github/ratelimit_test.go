@@ -0,0 +1,224 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(10*time.Second).Unix(), 10)
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		attempt int
+		want    time.Duration
+		retry   bool
+	}{
+		{name: "429 with Retry-After", status: 429, header: map[string]string{"Retry-After": "2"}, want: 2 * time.Second, retry: true},
+		{name: "429 without headers", status: 429, want: secondaryRateLimitWait, retry: true},
+		{name: "secondary rate limit 403", status: 403, header: map[string]string{"Retry-After": "5"}, want: 5 * time.Second, retry: true},
+		{name: "primary rate limit 403", status: 403, header: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}, want: 10 * time.Second, retry: true},
+		{name: "permission 403", status: 403, header: map[string]string{"X-RateLimit-Remaining": "4999"}},
+		{name: "500 first attempt", status: 500, want: time.Second, retry: true},
+		{name: "502 second attempt", status: 502, attempt: 1, want: 2 * time.Second, retry: true},
+		{name: "503 third attempt", status: 503, attempt: 2, want: 4 * time.Second, retry: true},
+		{name: "504", status: 504, want: time.Second, retry: true},
+		{name: "404", status: 404},
+		{name: "200", status: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for k, v := range tt.header {
+				resp.Header.Set(k, v)
+			}
+			got, retry := retryAfter(resp, tt.attempt)
+			if retry != tt.retry {
+				t.Fatalf("retry = %v, want %v", retry, tt.retry)
+			}
+			// リセット時刻までの待ち時間は秒単位に丸められる
+			if got > tt.want || got < tt.want-time.Second {
+				t.Errorf("wait = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	tests := []struct {
+		name      string
+		rateLimit RateLimit
+		want      int
+	}{
+		{name: "unknown", want: maxConcurrency},
+		{name: "full", rateLimit: RateLimit{Limit: 5000, Remaining: 5000}, want: maxConcurrency},
+		{name: "half", rateLimit: RateLimit{Limit: 5000, Remaining: 2500}, want: maxConcurrency},
+		{name: "below half", rateLimit: RateLimit{Limit: 5000, Remaining: 2000}, want: maxConcurrency / 2},
+		{name: "below a quarter", rateLimit: RateLimit{Limit: 5000, Remaining: 1000}, want: 2},
+		{name: "below 5%", rateLimit: RateLimit{Limit: 5000, Remaining: 200}, want: 1},
+		{name: "exhausted", rateLimit: RateLimit{Limit: 5000}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newRateLimitTransport(nil)
+			tr.rateLimit = tt.rateLimit
+			if got := tr.concurrency(); got != tt.want {
+				t.Errorf("concurrency() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitForReset(t *testing.T) {
+	tests := []struct {
+		name      string
+		rateLimit RateLimit
+		canceled  bool
+		wantErr   bool
+		wantWait  bool
+	}{
+		{name: "unknown"},
+		{name: "remaining", rateLimit: RateLimit{Limit: 5000, Remaining: 1, Reset: time.Now().Add(time.Hour)}},
+		{name: "already reset", rateLimit: RateLimit{Limit: 5000, Reset: time.Now().Add(-time.Second)}},
+		{name: "resets soon", rateLimit: RateLimit{Limit: 5000, Reset: time.Now().Add(100 * time.Millisecond)}, wantWait: true},
+		{name: "resets too late", rateLimit: RateLimit{Limit: 5000, Reset: time.Now().Add(time.Hour)}, wantErr: true},
+		{name: "canceled", rateLimit: RateLimit{Limit: 5000, Reset: time.Now().Add(10 * time.Second)}, canceled: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newRateLimitTransport(nil)
+			tr.rateLimit = tt.rateLimit
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.canceled {
+				cancel()
+			}
+			defer cancel()
+			start := time.Now()
+			err := tr.waitForReset(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("waitForReset() = %v, want error %v", err, tt.wantErr)
+			}
+			if waited := time.Since(start) >= 50*time.Millisecond; waited != tt.wantWait {
+				t.Errorf("waited %s, want waiting %v", time.Since(start), tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestRateLimitTransportRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		respond  func(w http.ResponseWriter, n int32)
+		want     int
+		requests int32
+	}{
+		{
+			name: "retries 429",
+			respond: func(w http.ResponseWriter, n int32) {
+				if n == 1 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+				}
+			},
+			want:     http.StatusOK,
+			requests: 2,
+		},
+		{
+			name: "retries rate limited 403",
+			respond: func(w http.ResponseWriter, n int32) {
+				if n == 1 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusForbidden)
+				}
+			},
+			want:     http.StatusOK,
+			requests: 2,
+		},
+		{
+			name: "passes permission 403 through",
+			respond: func(w http.ResponseWriter, n int32) {
+				w.Header().Set("X-RateLimit-Remaining", "4999")
+				w.WriteHeader(http.StatusForbidden)
+			},
+			want:     http.StatusForbidden,
+			requests: 1,
+		},
+		{
+			name: "gives up after the retries",
+			respond: func(w http.ResponseWriter, n int32) {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			want:     http.StatusTooManyRequests,
+			requests: maxRetries + 1,
+		},
+		{
+			name: "does not wait longer than the limit",
+			respond: func(w http.ResponseWriter, n int32) {
+				w.Header().Set("Retry-After", strconv.Itoa(int(2*maxRateLimitWait/time.Second)))
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			want:     http.StatusTooManyRequests,
+			requests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.respond(w, requests.Add(1))
+			}))
+			defer srv.Close()
+
+			req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := newRateLimitTransport(nil).RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("sent %d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestRateLimitTransportWaitsForReset(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	}))
+	defer srv.Close()
+
+	tr := newRateLimitTransport(nil)
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if r := tr.RateLimit(); r.Limit != 5000 || r.Remaining != 0 {
+		t.Errorf("RateLimit() = %+v, want 0/5000", r)
+	}
+	// 使い切った後はリセットまで待てないので送らずに失敗する
+	if _, err := tr.RoundTrip(req); err == nil || errors.Is(err, context.Canceled) {
+		t.Errorf("RoundTrip() = %v, want the rate limit error", err)
+	}
+}
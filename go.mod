@@ -7,6 +7,8 @@ toolchain go1.24.1
 require (
 	github.com/briandowns/spinner v1.23.2
 	github.com/cli/go-gh/v2 v2.12.0
+	github.com/sourcegraph/conc v0.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -21,12 +23,10 @@ require (
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/muesli/termenv v0.16.0 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
-	github.com/sourcegraph/conc v0.3.0 // indirect
 	github.com/thlib/go-timezone-local v0.0.6 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
 	go.uber.org/multierr v1.9.0 // indirect
 	golang.org/x/sys v0.31.0 // indirect
 	golang.org/x/term v0.30.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
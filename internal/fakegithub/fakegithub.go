@@ -0,0 +1,286 @@
+// Package fakegithub provides an in-process fake of the parts of the GitHub
+// REST and GraphQL APIs that gh-otui uses, for tests.
+package fakegithub
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Owner struct {
+	Login string `json:"login"`
+	Type  string `json:"type"`
+}
+
+type Repository struct {
+	Name        string    `json:"name"`
+	FullName    string    `json:"full_name"`
+	HtmlUrl     string    `json:"html_url"`
+	Owner       Owner     `json:"owner"`
+	Description string    `json:"description,omitempty"`
+	Language    string    `json:"language,omitempty"`
+	Stars       int       `json:"stargazers_count"`
+	PushedAt    time.Time `json:"pushed_at"`
+	Private     bool      `json:"private"`
+	Archived    bool      `json:"archived"`
+}
+
+// Server is a fake GitHub host. Listings such as "orgs/acme/repos" or
+// "user/repos?affiliation=owner" are paginated with Link headers like the
+// real API.
+type Server struct {
+	*httptest.Server
+	host string
+
+	mu       sync.Mutex
+	login    string
+	scopes   string
+	revoked  bool
+	orgs     []string
+	owners   map[string]Owner
+	listings map[string][]Repository
+	failures map[string]int
+	requests []string
+}
+
+// New starts a fake of host, e.g. "github.com" or "ghe.example.com".
+// The caller must Close it.
+func New(host string) *Server {
+	s := &Server{
+		host:     host,
+		login:    "octocat",
+		scopes:   "repo, read:org",
+		owners:   make(map[string]Owner),
+		listings: make(map[string][]Repository),
+		failures: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *Server) Host() string {
+	return s.host
+}
+
+// SetLogin sets the login of the authenticated user.
+func (s *Server) SetLogin(login string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.login = login
+}
+
+// SetScopes sets the OAuth scopes the server reports for the token.
+func (s *Server) SetScopes(scopes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scopes = strings.Join(scopes, ", ")
+}
+
+// RevokeToken makes the server reject every request with 401 Bad
+// credentials, like a host whose token was revoked.
+func (s *Server) RevokeToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked = true
+}
+
+// Repositories returns n repositories of owner on the server's host.
+func (s *Server) Repositories(owner string, n int) []Repository {
+	repos := make([]Repository, 0, n)
+	for i := range n {
+		repos = append(repos, s.Repository(owner, fmt.Sprintf("repo%03d", i)))
+	}
+	return repos
+}
+
+func (s *Server) Repository(owner, name string) Repository {
+	return Repository{
+		Name:     name,
+		FullName: owner + "/" + name,
+		HtmlUrl:  fmt.Sprintf("https://%s/%s/%s", s.host, owner, name),
+		Owner:    Owner{Login: owner, Type: "User"},
+	}
+}
+
+// AddOrganization makes the authenticated user a member of org with repos.
+func (s *Server) AddOrganization(org string, repos ...Repository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orgs = append(s.orgs, org)
+	s.owners[strings.ToLower(org)] = Owner{Login: org, Type: "Organization"}
+	s.listings["orgs/"+org+"/repos"] = repos
+}
+
+// AddUser adds a user the authenticated user is not related to.
+func (s *Server) AddUser(user string, repos ...Repository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.owners[strings.ToLower(user)] = Owner{Login: user, Type: "User"}
+	s.listings["users/"+user+"/repos"] = repos
+}
+
+// SetListing sets the repositories returned by listing.
+func (s *Server) SetListing(listing string, repos ...Repository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listings[listing] = repos
+}
+
+// FailPage makes page of listing respond with 404.
+func (s *Server) FailPage(listing string, page int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[listing] = page
+}
+
+// Requests returns the paths and queries requested so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(5000-len(s.requests)))
+	w.Header().Set("X-RateLimit-Reset", "4102444800")
+	w.Header().Set("X-OAuth-Scopes", s.scopes)
+	if s.revoked {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Bad credentials"})
+		return
+	}
+
+	path := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/v3"), "/")
+	switch {
+	case path == "graphql":
+		writeJSON(w, map[string]any{"data": map[string]any{"viewer": map[string]string{"login": s.login}}})
+	case path == "user":
+		writeJSON(w, Owner{Login: s.login, Type: "User"})
+	case path == "user/orgs":
+		orgs := make([]Owner, 0, len(s.orgs))
+		for _, org := range s.orgs {
+			orgs = append(orgs, Owner{Login: org, Type: "Organization"})
+		}
+		writeJSON(w, orgs)
+	case path == "search/repositories":
+		s.serveSearch(w, r)
+	case strings.HasPrefix(path, "users/") && strings.Count(path, "/") == 1:
+		owner, ok := s.owners[strings.ToLower(strings.TrimPrefix(path, "users/"))]
+		if !ok {
+			notFound(w)
+			return
+		}
+		writeJSON(w, owner)
+	default:
+		listing := path
+		if a := r.URL.Query().Get("affiliation"); a != "" {
+			listing += "?affiliation=" + a
+		}
+		s.serveListing(w, r, listing)
+	}
+}
+
+func (s *Server) serveListing(w http.ResponseWriter, r *http.Request, listing string) {
+	repos, ok := s.listings[listing]
+	// 認証ユーザーの一覧は設定されていなくても空で存在する
+	ok = ok || strings.HasPrefix(listing, "user/")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = max(page, 1)
+	if !ok || s.failures[listing] == page {
+		notFound(w)
+		return
+	}
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage <= 0 {
+		perPage = 30
+	}
+	lastPage := max((len(repos)+perPage-1)/perPage, 1)
+	if lastPage > 1 {
+		link := func(p int) string {
+			u := *r.URL
+			q := u.Query()
+			q.Set("page", strconv.Itoa(p))
+			u.RawQuery = q.Encode()
+			return "https://" + apiHost(s.host) + u.RequestURI()
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next", <%s>; rel="last"`, link(min(page+1, lastPage)), link(lastPage)))
+	}
+	start := min((page-1)*perPage, len(repos))
+	writeJSON(w, repos[start:min(start+perPage, len(repos))])
+}
+
+// serveSearch matches the query against the names of every repository known
+// to the server.
+func (s *Server) serveSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.ToLower(r.URL.Query().Get("q"))
+	seen := make(map[string]bool)
+	items := []Repository{}
+	for _, listing := range slices.Sorted(maps.Keys(s.listings)) {
+		for _, repo := range s.listings[listing] {
+			if !seen[repo.FullName] && strings.Contains(strings.ToLower(repo.FullName), q) {
+				seen[repo.FullName] = true
+				items = append(items, repo)
+			}
+		}
+	}
+	writeJSON(w, map[string]any{"total_count": len(items), "items": items})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func notFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Not Found"})
+}
+
+func apiHost(host string) string {
+	if host == "github.com" {
+		return "api.github.com"
+	}
+	return host
+}
+
+// Transport routes API requests for the hosts of servers to them. Requests
+// for other hosts fail.
+func Transport(servers ...*Server) http.RoundTripper {
+	targets := make(map[string]*url.URL, len(servers))
+	for _, s := range servers {
+		u, _ := url.Parse(s.URL)
+		targets[apiHost(s.host)] = u
+	}
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		target, ok := targets[req.URL.Host]
+		if !ok {
+			return nil, fmt.Errorf("fakegithub: no server for %s", req.URL.Host)
+		}
+		r := req.Clone(req.Context())
+		r.URL.Scheme = target.Scheme
+		r.URL.Host = target.Host
+		return http.DefaultTransport.RoundTrip(r)
+	})
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"os/signal"
 	"slices"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,6 +20,7 @@ import (
 	"github.com/cli/go-gh/v2/pkg/auth"
 	"github.com/n3xem/gh-otui/cache"
 	"github.com/n3xem/gh-otui/cmd"
+	"github.com/n3xem/gh-otui/config"
 	"github.com/n3xem/gh-otui/github"
 	"github.com/n3xem/gh-otui/models"
 	"github.com/sourcegraph/conc/pool"
@@ -21,42 +28,96 @@ import (
 	"github.com/briandowns/spinner"
 )
 
-func checkCloneStatus(repos []models.Repository, ghqRoot string) []models.Repository {
-	for i, repo := range repos {
-		path, _ := repo.GetClonePath(ghqRoot)
-		if _, err := os.Stat(path); err == nil {
-			repos[i].Cloned = true
-		}
-	}
-	return repos
-}
-
+// deduplicateRepositories merges the repositories found more than once, e.g.
+// in the cache and among the local clones, which marks cached repositories as
+// cloned without looking them up on disk.
 func deduplicateRepositories(repos []models.Repository) []models.Repository {
-	seen := make(map[string]bool)
+	seen := make(map[string]int)
 	var result []models.Repository
 
 	for _, repo := range repos {
 		// Create a unique key for each repository
-		key := fmt.Sprintf("%s/%s/%s", repo.Host, repo.OrgName, repo.Name)
-		if !seen[key] {
-			seen[key] = true
+		key := strings.ToLower(repo.Key())
+		i, ok := seen[key]
+		if !ok {
+			seen[key] = len(result)
 			result = append(result, repo)
+			continue
+		}
+		result[i].Cloned = result[i].Cloned || repo.Cloned
+		// 検索結果が先に来てもキャッシュ側のピン留めを残す
+		result[i].Pinned = result[i].Pinned || repo.Pinned
+		if result[i].Status == nil {
+			result[i].Status = repo.Status
+		}
+		// 複数のソースで見つかったリポジトリはソースをまとめる
+		for _, source := range repo.Sources {
+			if !result[i].HasSource(source) {
+				result[i].Sources = append(slices.Clip(result[i].Sources), source)
+			}
 		}
 	}
 	return result
 }
 
-func updateCache(ctx context.Context) (updated bool, err error) {
-	hosts := auth.KnownHosts()
-	gihubClients := make([]*github.Client, 0, len(hosts))
+// newClient creates the API client for host. Tests replace it to talk to a
+// fake server.
+var newClient = func(host string) (*github.Client, error) {
+	return github.NewClient(api.ClientOptions{
+		Host: host,
+	})
+}
+
+// connectHosts creates a client for every known host. Hosts whose client
+// cannot be created or whose token is rejected are skipped and returned as failures.
+func connectHosts(ctx context.Context, hosts []string, maxPages int) ([]*github.Client, map[string]error) {
+	var mu sync.Mutex
+	clients := make([]*github.Client, 0, len(hosts))
+	failures := make(map[string]error)
+	p := pool.New().WithContext(ctx)
 	for _, host := range hosts {
-		client, err := github.NewClient(api.ClientOptions{
-			Host: host,
+		p.Go(func(ctx context.Context) error {
+			client, err := newClient(host)
+			if err == nil {
+				client.SetMaxPages(maxPages)
+				err = client.VerifyAuth(ctx)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if github.IsAuthError(err) {
+					err = fmt.Errorf("%w (run `gh auth login --hostname %s`)", err, host)
+				}
+				failures[host] = err
+				return nil
+			}
+			clients = append(clients, client)
+			return nil
 		})
-		if err != nil {
-			return false, err
-		}
-		gihubClients = append(gihubClients, client)
+	}
+	_ = p.Wait()
+	return clients, failures
+}
+
+func hostWarnings(failures map[string]error) error {
+	hosts := slices.Sorted(maps.Keys(failures))
+	errs := make([]error, 0, len(hosts))
+	for _, host := range hosts {
+		errs = append(errs, fmt.Errorf("warning: skipped %s: %w", host, failures[host]))
+	}
+	return errors.Join(errs...)
+}
+
+// updateCache fetches repositories from every host and owner allowed by cfg.
+// Filters given as flags only affect the listing, so they are not passed here
+// and a one-off invocation does not leave the cache incomplete.
+func updateCache(ctx context.Context, cfg *config.Config, log io.Writer) (updated bool, err error) {
+	hosts := slices.DeleteFunc(auth.KnownHosts(), func(host string) bool {
+		return !cfg.AllowHost(host)
+	})
+	gihubClients, failures := connectHosts(ctx, hosts, cfg.PageLimit())
+	if ctx.Err() != nil {
+		return false, ctx.Err()
 	}
 	p := pool.NewWithResults[[]*models.RepositoryGroup]().WithErrors().WithContext(ctx).WithMaxGoroutines(5)
 	// 自分のリポジトリを取得
@@ -68,6 +129,9 @@ func updateCache(ctx context.Context) (updated bool, err error) {
 				if err != nil {
 					return nil, err
 				}
+				if !cfg.AllowOwner(g.Host(), g.Organization()) {
+					return nil, nil
+				}
 				if err := cache.Save(ctx, g); err != nil {
 					return nil, err
 				}
@@ -85,15 +149,26 @@ func updateCache(ctx context.Context) (updated bool, err error) {
 			}
 			gp := pool.NewWithResults[*models.RepositoryGroup]().WithErrors().WithContext(ctx)
 			for _, org := range orgs {
+				if !cfg.AllowOwner(client.Host(), org.Name()) {
+					continue
+				}
+				gp.Go(func(ctx context.Context) (*models.RepositoryGroup, error) {
+					return fetchOwnerRepositories(ctx, org)
+				})
+			}
+			// 設定で追加したorganizationやユーザーのリポジトリを取得
+			for _, name := range cfg.ExtraOwners[client.Host()] {
+				if !cfg.AllowOwner(client.Host(), name) || slices.ContainsFunc(orgs, func(org *github.OwnerOrganization) bool {
+					return strings.EqualFold(org.Name(), name)
+				}) {
+					continue
+				}
 				gp.Go(func(ctx context.Context) (*models.RepositoryGroup, error) {
-					g, err := org.FetchRepositories(ctx)
+					owner, err := github.NewOwner(ctx, name, client)
 					if err != nil {
 						return nil, err
 					}
-					if err := cache.Save(ctx, g); err != nil {
-						return nil, err
-					}
-					return g, nil
+					return fetchOwnerRepositories(ctx, owner)
 				})
 			}
 			return gp.Wait()
@@ -108,6 +183,10 @@ func updateCache(ctx context.Context) (updated bool, err error) {
 			}
 			gp := pool.NewWithResults[*models.RepositoryGroup]().WithErrors().WithContext(ctx)
 			for g := range gs {
+				// 追加したオーナーのグループは全リポジトリを取得済みなので上書きしない
+				if !cfg.AllowOwner(g.Host(), g.Organization()) || cfg.IsExtraOwner(g.Host(), g.Organization()) {
+					continue
+				}
 				gp.Go(func(ctx context.Context) (*models.RepositoryGroup, error) {
 					if err := cache.Save(ctx, g); err != nil {
 						return nil, err
@@ -119,19 +198,81 @@ func updateCache(ctx context.Context) (updated bool, err error) {
 		})
 	}
 
+	// スターやウォッチしているリポジトリを取得
+	for _, client := range gihubClients {
+		for _, source := range cfg.Sources {
+			p.Go(func(ctx context.Context) ([]*models.RepositoryGroup, error) {
+				gs, err := github.FetchSourceRepositories(ctx, client, github.Source(source))
+				if err != nil {
+					return nil, err
+				}
+				gp := pool.NewWithResults[*models.RepositoryGroup]().WithErrors().WithContext(ctx)
+				var owners []string
+				for g := range gs {
+					if !cfg.AllowOwner(g.Host(), g.Organization()) {
+						continue
+					}
+					owners = append(owners, g.Organization())
+					gp.Go(func(ctx context.Context) (*models.RepositoryGroup, error) {
+						if err := cache.Save(ctx, g); err != nil {
+							return nil, err
+						}
+						return g, nil
+					})
+				}
+				saved, err := gp.Wait()
+				if err != nil {
+					return saved, err
+				}
+				// スターやウォッチを外したオーナーのファイルを消す
+				return saved, cache.PruneSource(ctx, client.Host(), source, owners)
+			})
+		}
+		// 設定から外したソースのファイルを消す
+		for _, source := range github.Sources {
+			if slices.Contains(cfg.Sources, string(source)) {
+				continue
+			}
+			p.Go(func(ctx context.Context) ([]*models.RepositoryGroup, error) {
+				return nil, cache.PruneSource(ctx, client.Host(), string(source), nil)
+			})
+		}
+	}
+
 	gg, err := p.Wait()
+	report := cache.Report{HostErrors: failures}
+	var truncated []error
+	for _, client := range gihubClients {
+		fmt.Fprintf(log, "%s: %s\n", client.Host(), client.RateLimit())
+		for _, t := range client.Truncations() {
+			truncated = append(truncated, fmt.Errorf("warning: %s (raise max_pages to fetch more)", t))
+			report.Truncations = append(report.Truncations, cache.Truncation(t))
+		}
+	}
+	err = errors.Join(hostWarnings(failures), errors.Join(truncated...), err)
 	gs := flatten(gg)
 	someCached := slices.ContainsFunc(gs, func(g *models.RepositoryGroup) bool {
 		return g != nil
 	})
 	if !someCached {
-		return false, err
+		return false, errors.Join(err, cache.Record(ctx, report))
 	}
-	e := cache.Done(ctx)
+	e := cache.Done(ctx, report)
 	err = errors.Join(err, e)
 	return e == nil, err
 }
 
+func fetchOwnerRepositories(ctx context.Context, owner *github.OwnerOrganization) (*models.RepositoryGroup, error) {
+	g, err := owner.FetchRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Save(ctx, g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
 func flatten[T any](slices [][]T) []T {
 	length := 0
 	for _, slice := range slices {
@@ -153,36 +294,132 @@ func loading(msg string, f func() error) error {
 }
 
 const (
-	cmdClear = "clear"
+	cmdClear    = "clear"
+	cmdDoctor   = "doctor"
+	cmdAddOwner = "add-owner"
+	cmdSearch   = "search"
+	cmdRecent   = "recent"
+	cmdPin      = "pin"
+	cmdUnpin    = "unpin"
+	cmdConfig   = "config"
+	cmdAction   = "action"
+	cmdCache    = "cache"
 )
 
-func run(ctx context.Context, args []string) error {
-	if err := cmd.CheckRequiredCommands(); err != nil {
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	opts, args, err := parseArgs(args[1:], stdout, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if len(args) == 1 && args[0] == cmdDoctor {
+		return doctor(ctx, opts)
+	}
+
+	if len(args) > 0 && args[0] == cmdConfig {
+		// 不正な値を config set で直せるよう検証せずに読み込む
+		cfg, err := config.Read()
+		if err != nil {
+			return err
+		}
+		return configure(cfg, args[1:], opts.stdout, opts.stderr)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 && args[0] == cmdAddOwner {
+		if opts.offline {
+			return offlineError("add owners")
+		}
+		// 設定ファイルに保存するので、上書きした値は cfg に入れない
+		view, err := cfg.WithEnv()
+		if err != nil {
+			return err
+		}
+		maxPages := view.PageLimit()
+		if opts.maxPages > 0 {
+			maxPages = opts.maxPages
+		}
+		return addOwner(ctx, cfg, maxPages, args[1:], opts.stdout, opts.stderr)
+	}
+	if len(args) > 0 && (args[0] == cmdPin || args[0] == cmdUnpin) {
+		return pin(cfg, args[0] == cmdPin, args[1:], opts.stdout, opts.stderr)
+	}
+	// 優先順位はフラグ、環境変数、設定ファイルの順
+	cfg, err = cfg.WithEnv()
+	if err != nil {
+		return err
+	}
+	if opts.maxPages > 0 {
+		cfg.MaxPages = opts.maxPages
+	}
+	if opts.selector != "" {
+		cfg.Selector = opts.selector
+	}
+
+	if len(args) > 0 && args[0] == cmdCache {
+		return cacheCommand(ctx, opts, cfg, args[1:])
+	}
+
+	if err := cmd.CheckRequiredCommands(cfg.Cloner(), cfg.Selector); err != nil {
 		return err
 	}
 
-	ghqRoot, err := cmd.GetGhqRoot(ctx)
+	ws, err := cmd.NewWorkspace(ctx, opts.runner, cmd.WorkspaceOptions{
+		Command:    cfg.Cloner(),
+		Protocol:   cfg.Protocol(),
+		ExtraRoots: cfg.CloneRootDirs(),
+	})
 	if err != nil {
 		return fmt.Errorf("failed to get ghq root: %w", err)
 	}
 
-	if len(args) == 2 && args[1] == cmdClear {
+	if len(args) > 0 && args[0] == cmdAction {
+		return runAction(ctx, opts, cfg, ws, args[1:])
+	}
+
+	if len(args) == 1 && args[0] == cmdClear {
 		if err := cache.Clear(ctx); err != nil {
 			return fmt.Errorf("failed to clear cache: %w", err)
 		}
 		return nil
 	}
 
+	if len(args) > 0 && args[0] == cmdRecent {
+		return recent(ctx, opts, cfg, ws, args[1:])
+	}
+
+	if len(args) > 0 && args[0] == cmdSearch {
+		return search(ctx, opts, cfg, ws, args[1:])
+	}
+
 	md, err := cache.LoadMetadata(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to load cache: %w", err)
 	}
+	// 一覧が欠けていることは -verbose なしでも伝える
+	for _, t := range md.Truncations() {
+		fmt.Fprintf(opts.stderr, "warning: %s/%s has %d pages but only %d were cached (raise max_pages to fetch more)\n", t.Host, t.Listing, t.LastPage, t.Fetched)
+	}
+
+	if (!md.Initialized() || md.IsStale(cfg.TTL())) && detectOffline(ctx, opts, cfg) {
+		fmt.Fprintln(opts.stderr, offlineNotice)
+	}
+	if !md.Initialized() && opts.offline {
+		fmt.Fprintln(opts.stderr, "The repositories have not been fetched yet: listing the local clones only")
+	}
 
-	if !md.Initialized() {
+	if !md.Initialized() && !opts.offline {
 		// 同期的なキャッシュ更新
 		var updated bool
 		err := loading("Fetching repositories...", func() error {
-			u, err := updateCache(ctx)
+			u, err := updateCache(ctx, cfg, opts.log())
 			updated = u
 			return err
 		})
@@ -190,32 +427,51 @@ func run(ctx context.Context, args []string) error {
 			return err
 		}
 		// 少なくとも１つキャッシュが更新されたなら続行する。
+		if err != nil {
+			fmt.Fprintln(opts.stderr, err)
+		}
 	}
 
-	if md.Initialized() && md.IsStale() {
+	if md.Initialized() && md.IsStale(cfg.TTL()) && !opts.offline {
 		// 非同期的なキャッシュ更新
 		ctx, cancel := context.WithCancel(ctx)
 		defer cancel()
 		p := pool.New().WithErrors().WithContext(ctx)
+		// セレクタの表示を崩さないよう、詳細ログは終了後に出力する
+		var log bytes.Buffer
 		p.Go(func(ctx context.Context) error {
-			_, err := updateCache(ctx)
+			_, err := updateCache(ctx, cfg, &log)
 			return err
 		})
 		defer func() {
 			cancel()
-			if err := p.Wait(); err != nil {
+			err := p.Wait()
+			if opts.verbose {
+				opts.stderr.Write(log.Bytes())
+			}
+			if err != nil {
 				if errors.Is(err, context.Canceled) {
 					return
 				}
-				fmt.Fprintln(os.Stderr, err)
+				fmt.Fprintln(opts.stderr, err)
 			}
 		}()
 	}
 
+	repos, err := listRepositories(ctx, opts, cfg, ws)
+	if err != nil {
+		return err
+	}
+	return selectRepository(ctx, opts, cfg, repos, ws)
+}
+
+// listRepositories returns the cached and ghq managed repositories that pass
+// the configured and flag filters, with their clone status.
+func listRepositories(ctx context.Context, opts *options, cfg *config.Config, ws cmd.Workspace) ([]models.Repository, error) {
 	// Load and process repositories
 	repositoryGroups, err := cache.FetchRepositories(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to fetch repositories: %w", err)
+		return nil, fmt.Errorf("failed to fetch repositories: %w", err)
 	}
 	allRepos := make([]models.Repository, 0)
 	for _, repos := range repositoryGroups {
@@ -223,18 +479,125 @@ func run(ctx context.Context, args []string) error {
 	}
 
 	// Add local repositories from ghq
-	ghqRepos, err := cmd.FetchGHQRepositories(ctx)
+	ghqRepos, err := cmd.FetchClonedRepositories(ctx, ws)
 	if err != nil {
-		return fmt.Errorf("failed to fetch ghq repositories: %w", err)
+		return nil, fmt.Errorf("failed to fetch ghq repositories: %w", err)
 	}
 	allRepos = append(allRepos, ghqRepos...)
 
+	repos := filterRepositories(opts, cfg, allRepos)
+	if opts.status || cfg.Status {
+		addStatuses(ctx, opts, repos, ws)
+	}
+
+	for i := range repos {
+		repos[i].Pinned = cfg.IsPinned(repos[i].Key())
+	}
+	mode := cfg.SortMode()
+	if opts.sort != "" {
+		mode = opts.sort
+	}
+	var frecency map[string]float64
+	if mode == models.SortFrecency {
+		history, err := cache.LoadHistory(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load history: %w", err)
+		}
+		frecency = history.Frecency(time.Now())
+	}
+	if err := models.SortRepositories(repos, mode, frecency); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// recordSelection adds the selected repository and what was done with it to
+// the history.
+func recordSelection(ctx context.Context, repo models.Repository, action string) error {
+	history, err := cache.LoadHistory(ctx)
+	if err != nil {
+		return err
+	}
+	history.Add(repo.Key(), action, time.Now())
+	return cache.SaveHistory(ctx, history)
+}
+
+// statusTimeout bounds the time spent checking working copies before the
+// selector opens. Working copies not checked in time show their cached status.
+const statusTimeout = 2 * time.Second
+
+// addStatuses sets the working copy status of the cloned repositories among
+// repos. Statuses checked within the last few minutes are reused.
+func addStatuses(ctx context.Context, opts *options, repos []models.Repository, ws cmd.Workspace) {
+	statuses, err := cache.LoadStatuses(ctx)
+	if err != nil {
+		fmt.Fprintf(opts.log(), "failed to load working copy statuses: %v\n", err)
+		statuses = make(map[string]cache.Status)
+	}
+	paths := make(map[int]string)
+	var stale []string
+	for i, repo := range repos {
+		if !repo.Cloned {
+			continue
+		}
+		path, ok := cmd.FindClone(repo, ws.Roots())
+		if !ok {
+			continue
+		}
+		paths[i] = path
+		if s, ok := statuses[path]; !ok || s.IsStale() {
+			stale = append(stale, path)
+		}
+	}
+
+	if len(stale) > 0 {
+		checkCtx, cancel := context.WithTimeout(ctx, statusTimeout)
+		checked := cmd.WorkingCopyStatuses(checkCtx, opts.runner, stale)
+		cancel()
+		now := time.Now()
+		for path, status := range checked {
+			statuses[path] = cache.Status{WorkingCopy: status, CheckedAt: now}
+		}
+		// 削除されたクローンの状態は保存しない
+		current := make(map[string]bool, len(paths))
+		for _, path := range paths {
+			current[path] = true
+		}
+		maps.DeleteFunc(statuses, func(path string, _ cache.Status) bool {
+			return !current[path]
+		})
+		if err := cache.SaveStatuses(ctx, statuses); err != nil {
+			fmt.Fprintf(opts.log(), "failed to save working copy statuses: %v\n", err)
+		}
+	}
+
+	for i, path := range paths {
+		if s, ok := statuses[path]; ok {
+			repos[i].Status = &s.WorkingCopy
+		}
+	}
+}
+
+func filterRepositories(opts *options, cfg *config.Config, repos []models.Repository) []models.Repository {
 	// Remove duplicates
-	allRepos = deduplicateRepositories(allRepos)
+	repos = deduplicateRepositories(repos)
 
-	allRepos = checkCloneStatus(allRepos, ghqRoot)
+	view := opts.filter(cfg)
+	return slices.DeleteFunc(repos, func(repo models.Repository) bool {
+		return !view.AllowHost(repo.Host) || !view.AllowOwner(repo.Host, repo.OrgName) || !opts.allowSource(repo)
+	})
+}
 
-	selected, err := cmd.Select(ctx, allRepos)
+// selectRepository lets the user pick one of repos and opens it.
+func selectRepository(ctx context.Context, opts *options, cfg *config.Config, repos []models.Repository, ws cmd.Workspace) error {
+	selectorOpts, err := selectorOptions(opts, cfg)
+	if err != nil {
+		return err
+	}
+	selectorOpts.Reload = reloadCommand(opts)
+	selectorOpts.Pin = selfCommand(opts, cmdPin, "--toggle", "--")
+	selectorOpts.List = selfCommand(opts, cmdSearch, "--print")
+	selected, err := cmd.Select(ctx, opts.runner, repos, selectorOpts)
 	if err != nil {
 		if errors.Is(err, cmd.ErrRepositoryNotSelected) {
 			return nil
@@ -242,25 +605,75 @@ func run(ctx context.Context, args []string) error {
 		return fmt.Errorf("error selecting repository: %w", err)
 	}
 
-	if !selected.Cloned {
-		err := loading(
-			fmt.Sprintf("Cloning %s/%s...", selected.OrgName, selected.Name),
-			func() error {
-				return cmd.CloneRepository(ctx, selected.GetGitURL())
-			})
-		if err != nil {
-			return fmt.Errorf("failed to clone repository: %w", err)
-		}
+	return openRepository(ctx, opts, cfg, *selected, ws)
+}
+
+// selectorOptions returns the configured selector, line format and actions.
+func selectorOptions(opts *options, cfg *config.Config) (cmd.SelectorOptions, error) {
+	format, err := cfg.Format()
+	if err != nil {
+		return cmd.SelectorOptions{}, err
+	}
+	selectorOpts := cmd.SelectorOptions{Selector: cfg.Selector, Format: format}
+	for _, a := range cfg.Actions {
+		selectorOpts.Actions = append(selectorOpts.Actions, cmd.Action{
+			Key:         a.Key,
+			Command:     selfCommand(opts, cmdAction, a.Key, "--"),
+			Description: a.Description,
+		})
+	}
+	return selectorOpts, nil
+}
+
+// openRepository clones repo if needed, prints its local path and records
+// the selection.
+func openRepository(ctx context.Context, opts *options, cfg *config.Config, repo models.Repository, ws cmd.Workspace) error {
+	// 既存のクローンはどのルートにあってもそのパスを出力する
+	if path, ok := cmd.FindClone(repo, ws.Roots()); ok {
+		record(ctx, opts, repo, cache.ActionOpen)
+		fmt.Fprintln(opts.stdout, path)
+		return nil
 	}
-	clonePath, err := selected.GetClonePath(ghqRoot)
+
+	// キャッシュが新しいと接続を確認していないので、クローン前に確認する
+	if detectOffline(ctx, opts, cfg) {
+		fmt.Fprintln(opts.stderr, offlineNotice)
+	}
+	if opts.offline {
+		return fmt.Errorf("%s is not cloned: %w", repo.Key(), offlineError("clone"))
+	}
+	root := cfg.CloneRoot(repo.Host, repo.OrgName)
+	if root == "" {
+		root = ws.Roots()[0]
+	}
+	clonePath, err := repo.GetClonePath(root)
 	if err != nil {
 		return fmt.Errorf("failed to get repository path: %w", err)
 	}
+	err = loading(
+		fmt.Sprintf("Cloning %s...", repo.FullName()),
+		func() error {
+			return ws.Clone(ctx, repo, root)
+		})
+	if err != nil {
+		return fmt.Errorf("failed to clone repository: %w", err)
+	}
+	record(ctx, opts, repo, cache.ActionClone)
 
-	fmt.Println(clonePath)
+	fmt.Fprintln(opts.stdout, clonePath)
 	return nil
 }
 
+// record adds the selection to the history unless it is a dry run.
+func record(ctx context.Context, opts *options, repo models.Repository, action string) {
+	if opts.dryRun {
+		return
+	}
+	if err := recordSelection(ctx, repo, action); err != nil {
+		fmt.Fprintf(opts.stderr, "warning: failed to record selection: %v\n", err)
+	}
+}
+
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(),
 		syscall.SIGINT,
@@ -268,7 +681,7 @@ func main() {
 		syscall.SIGHUP,
 	)
 	defer cancel()
-	if err := run(ctx, os.Args); err != nil {
+	if err := run(ctx, os.Args, os.Stdout, os.Stderr); err != nil {
 		if errors.Is(err, context.Canceled) {
 			return
 		}
@@ -0,0 +1,1240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/n3xem/gh-otui/cache"
+	"github.com/n3xem/gh-otui/cmd"
+	"github.com/n3xem/gh-otui/config"
+	"github.com/n3xem/gh-otui/github"
+	"github.com/n3xem/gh-otui/internal/fakegithub"
+	"github.com/n3xem/gh-otui/models"
+)
+
+type testEnv struct {
+	t       *testing.T
+	srv     *fakegithub.Server
+	home    string
+	ghqRoot string
+	ghqLog  string
+	gitLog  string
+}
+
+// setup isolates gh-otui from the user's environment: HOME, gh config, ghq
+// and the selector are replaced by a temporary directory, the stubs in
+// testdata/bin and a fake GitHub server.
+func setup(t *testing.T) *testEnv {
+	t.Helper()
+	home := t.TempDir()
+	bin, err := filepath.Abs(filepath.Join("testdata", "bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &testEnv{
+		t:       t,
+		srv:     fakegithub.New("github.com"),
+		home:    home,
+		ghqRoot: filepath.Join(home, "ghq"),
+		ghqLog:  filepath.Join(home, "ghq.log"),
+		gitLog:  filepath.Join(home, "git.log"),
+	}
+	t.Cleanup(e.srv.Close)
+
+	t.Setenv("HOME", home)
+	t.Setenv("GH_CONFIG_DIR", filepath.Join(home, ".config", "gh"))
+	t.Setenv("GH_OTUI_CONFIG", "")
+	t.Setenv("GH_HOST", "")
+	t.Setenv("GH_TOKEN", "token")
+	t.Setenv("GH_OTUI_SELECTOR", "fzf")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("GHQ_ROOT", e.ghqRoot)
+	t.Setenv("GHQ_LOG", e.ghqLog)
+	t.Setenv("GIT_LOG", e.gitLog)
+	t.Setenv("FZF_SELECT", "")
+	t.Setenv("FZF_INPUT", filepath.Join(home, "fzf.input"))
+	t.Setenv("FZF_WAIT_FOR", "")
+
+	orig := newClient
+	newClient = func(host string) (*github.Client, error) {
+		return github.NewClient(api.ClientOptions{
+			Host:         host,
+			AuthToken:    "token",
+			Transport:    fakegithub.Transport(e.srv),
+			LogIgnoreEnv: true,
+		})
+	}
+	t.Cleanup(func() { newClient = orig })
+	origReachable := reachable
+	reachable = func(context.Context, []string) bool { return true }
+	t.Cleanup(func() { reachable = origReachable })
+
+	e.srv.SetListing("user/repos?affiliation=owner", e.srv.Repository("octocat", "dotfiles"))
+	e.srv.AddOrganization("acme", e.srv.Repositories("acme", 3)...)
+	return e
+}
+
+func (e *testEnv) run(args ...string) (string, error) {
+	e.t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), append([]string{"gh-otui"}, args...), &stdout, &stderr)
+	if stderr.Len() > 0 {
+		e.t.Logf("stderr: %s", stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), err
+}
+
+func (e *testEnv) cachePath(elem ...string) string {
+	return filepath.Join(append([]string{e.home, ".config", "gh", "extensions", "gh-otui"}, elem...)...)
+}
+
+func (e *testEnv) ghqCalls() []string {
+	return readLog(e.ghqLog)
+}
+
+func (e *testEnv) gitCalls() []string {
+	return readLog(e.gitLog)
+}
+
+func readLog(name string) []string {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
+
+func (e *testEnv) writeConfig(content string) {
+	e.t.Helper()
+	dir := filepath.Join(e.home, ".config", "gh")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		e.t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "gh-otui.yml"), []byte(content), 0644); err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+func (e *testEnv) selectorInput() string {
+	b, _ := os.ReadFile(filepath.Join(e.home, "fzf.input"))
+	return string(b)
+}
+
+func TestRunFirstFetchSelectAndClone(t *testing.T) {
+	e := setup(t)
+	t.Setenv("FZF_SELECT", "acme/repo001")
+
+	out, err := e.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(e.ghqRoot, "github.com", "acme", "repo001")
+	if out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+	if !slices.Contains(e.gitCalls(), "clone git@github.com:acme/repo001 "+want) {
+		t.Errorf("repository was not cloned; git calls: %q", e.gitCalls())
+	}
+	for _, p := range []string{"_md.json", "github.com/acme.json", "github.com/octocat.json"} {
+		if _, err := os.Stat(e.cachePath(p)); err != nil {
+			t.Errorf("cache file %s was not written: %v", p, err)
+		}
+	}
+	if in := e.selectorInput(); !strings.Contains(in, "  github.com/octocat/dotfiles") {
+		t.Errorf("selector input does not list the user's repository:\n%s", in)
+	}
+}
+
+func TestRunSelectsClonedRepository(t *testing.T) {
+	e := setup(t)
+	t.Setenv("FZF_SELECT", "acme/repo002")
+	clone := filepath.Join(e.ghqRoot, "github.com", "acme", "repo002")
+	if err := os.MkdirAll(filepath.Join(clone, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := e.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != clone {
+		t.Errorf("printed %q, want %q", out, clone)
+	}
+	if !strings.Contains(e.selectorInput(), "✓ github.com/acme/repo002") {
+		t.Errorf("cloned repository is not marked:\n%s", e.selectorInput())
+	}
+	for _, call := range e.gitCalls() {
+		if strings.HasPrefix(call, "clone ") {
+			t.Errorf("cloned repository was cloned again: %s", call)
+		}
+	}
+}
+
+func TestRunRefreshesStaleCacheInBackground(t *testing.T) {
+	e := setup(t)
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// キャッシュを古くし、サーバーに新しいorganizationを追加する
+	md, err := json.Marshal(map[string]any{"last_updated": time.Now().Add(-2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(e.cachePath("_md.json"), md, 0644); err != nil {
+		t.Fatal(err)
+	}
+	e.srv.AddOrganization("newco", e.srv.Repository("newco", "service"))
+	t.Setenv("FZF_WAIT_FOR", e.cachePath("github.com", "newco.json"))
+	t.Setenv("FZF_SELECT", "acme/repo000")
+
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(e.cachePath("github.com", "newco.json")); err != nil {
+		t.Errorf("stale cache was not refreshed: %v", err)
+	}
+}
+
+func TestRunClear(t *testing.T) {
+	e := setup(t)
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.run("clear"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"_md.json", "github.com"} {
+		if _, err := os.Stat(e.cachePath(name)); !os.IsNotExist(err) {
+			t.Errorf("%s still exists: %v", name, err)
+		}
+	}
+	// 履歴は取得し直せないので残す
+	if _, err := os.Stat(e.cachePath("_history.json")); err != nil {
+		t.Errorf("history was removed: %v", err)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	e := setup(t)
+	t.Setenv("FZF_SELECT", "acme/repo001")
+
+	if _, err := e.run("-dry-run"); err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range e.gitCalls() {
+		if strings.HasPrefix(call, "clone ") {
+			t.Errorf("dry run cloned the repository: %s", call)
+		}
+	}
+}
+
+func TestRunClonesWithGhqWhenConfigured(t *testing.T) {
+	e := setup(t)
+	e.writeConfig("clone_command: ghq\n")
+	t.Setenv("FZF_SELECT", "acme/repo001")
+
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(e.ghqCalls(), "get git@github.com:acme/repo001") {
+		t.Errorf("repository was not cloned with ghq; ghq calls: %q", e.ghqCalls())
+	}
+	if len(e.gitCalls()) > 0 {
+		t.Errorf("git was run: %q", e.gitCalls())
+	}
+}
+
+func TestRunFindsCloneInSecondaryRoot(t *testing.T) {
+	e := setup(t)
+	secondary := filepath.Join(e.home, "work")
+	t.Setenv("GHQ_ROOT", e.ghqRoot+string(os.PathListSeparator)+secondary)
+	t.Setenv("FZF_SELECT", "acme/repo002")
+	clone := filepath.Join(secondary, "github.com", "acme", "repo002")
+	if err := os.MkdirAll(filepath.Join(clone, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := e.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != clone {
+		t.Errorf("printed %q, want %q", out, clone)
+	}
+	if !strings.Contains(e.selectorInput(), "✓ github.com/acme/repo002") {
+		t.Errorf("clone in the secondary root is not marked:\n%s", e.selectorInput())
+	}
+	if len(e.gitCalls()) > 0 {
+		t.Errorf("git was run: %q", e.gitCalls())
+	}
+}
+
+func TestRunClonesIntoConfiguredRoot(t *testing.T) {
+	e := setup(t)
+	e.writeConfig("clone_roots:\n  - match: github.com/acme\n    root: ~/work\n")
+	t.Setenv("FZF_SELECT", "acme/repo001")
+
+	out, err := e.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(e.home, "work", "github.com", "acme", "repo001")
+	if out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+	if !slices.Contains(e.gitCalls(), "clone git@github.com:acme/repo001 "+want) {
+		t.Errorf("repository was not cloned into the configured root; git calls: %q", e.gitCalls())
+	}
+}
+
+func TestRunSelectsNestedLocalRepository(t *testing.T) {
+	e := setup(t)
+	t.Setenv("FZF_SELECT", "gitlab.com/group/sub/tool")
+	clone := filepath.Join(e.ghqRoot, "gitlab.com", "group", "sub", "tool")
+	if err := os.MkdirAll(filepath.Join(clone, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := e.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != clone {
+		t.Errorf("printed %q, want %q", out, clone)
+	}
+}
+
+// BenchmarkCloneStatus measures how long marking 5000 cached repositories
+// takes when 1000 of them are cloned.
+func BenchmarkCloneStatus(b *testing.B) {
+	root := b.TempDir()
+	b.Setenv("GHQ_ROOT", root)
+	var cached []models.Repository
+	for o := range 20 {
+		owner := fmt.Sprintf("org%02d", o)
+		for n := range 250 {
+			repo := models.Repository{Host: "github.com", OrgName: owner, Name: fmt.Sprintf("repo%03d", n)}
+			cached = append(cached, repo)
+			if n%5 == 0 {
+				path, _ := repo.GetClonePath(root)
+				if err := os.MkdirAll(filepath.Join(path, ".git"), 0755); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+	ctx := context.Background()
+	ws, err := cmd.NewWorkspace(ctx, cmd.ExecRunner{}, cmd.WorkspaceOptions{Command: config.CloneWithGit})
+	if err != nil {
+		b.Fatal(err)
+	}
+	opts, cfg := &options{}, &config.Config{}
+
+	b.ResetTimer()
+	for range b.N {
+		clones, err := cmd.FetchClonedRepositories(ctx, ws)
+		if err != nil {
+			b.Fatal(err)
+		}
+		repos := filterRepositories(opts, cfg, append(slices.Clone(cached), clones...))
+		if n := len(slices.DeleteFunc(repos, func(r models.Repository) bool { return !r.Cloned })); n != 1000 {
+			b.Fatalf("%d repositories are marked cloned, want 1000", n)
+		}
+	}
+}
+
+func TestRunShowsWorkingCopyStatus(t *testing.T) {
+	e := setup(t)
+	t.Setenv("FZF_SELECT", "acme/repo002")
+	clone := filepath.Join(e.ghqRoot, "github.com", "acme", "repo002")
+	if err := os.MkdirAll(filepath.Join(clone, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	status := "# branch.head main\n# branch.ab +2 -1\n1 .M N... 100644 100644 100644 1234 1234 main.go\n"
+	if err := os.WriteFile(filepath.Join(clone, ".git", "stub-status"), []byte(status), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := e.run("-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != clone {
+		t.Errorf("printed %q, want %q", out, clone)
+	}
+	if !strings.Contains(e.selectorInput(), "✓ github.com/acme/repo002  main* ↑2 ↓1") {
+		t.Errorf("working copy status is not shown:\n%s", e.selectorInput())
+	}
+	if _, err := os.Stat(e.cachePath("_status.json")); err != nil {
+		t.Errorf("working copy status was not cached: %v", err)
+	}
+}
+
+func TestRunWithLineFormat(t *testing.T) {
+	e := setup(t)
+	api := e.srv.Repository("tools", "api")
+	api.Language = "Go"
+	e.srv.AddOrganization("tools", api)
+	e.writeConfig("line_format: '{{.Mark}} {{.FullName}}\t{{color \"cyan\" .Language}}'\n")
+	t.Setenv("FZF_SELECT", "tools/api")
+
+	out, err := e.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(e.ghqRoot, "github.com", "tools", "api"); out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+	if !strings.Contains(e.selectorInput(), "  tools/api         \x1b[36mGo\x1b[0m\n") {
+		t.Errorf("selector input does not use the line format:\n%q", e.selectorInput())
+	}
+}
+
+func TestRunSortsByFrecency(t *testing.T) {
+	e := setup(t)
+	t.Setenv("FZF_SELECT", "acme/repo002")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(e.selectorInput()), "\n")
+	if !strings.HasSuffix(lines[0], "github.com/acme/repo002") {
+		t.Errorf("the selected repository is not listed first:\n%s", e.selectorInput())
+	}
+}
+
+func TestRunSortFlag(t *testing.T) {
+	e := setup(t)
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run("-sort", "name"); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(e.selectorInput()), "\n") {
+		repo, err := models.ParsePath(cmd.LineKey(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, repo.Name)
+	}
+	if want := []string{"dotfiles", "repo000", "repo001", "repo002"}; !slices.Equal(names, want) {
+		t.Errorf("listed %q, want %q", names, want)
+	}
+
+	if _, err := e.run("-sort", "random"); err == nil {
+		t.Error("unknown sort mode was accepted")
+	}
+}
+
+func TestRunRecent(t *testing.T) {
+	e := setup(t)
+	for _, name := range []string{"acme/repo001", "octocat/dotfiles", "acme/repo001"} {
+		t.Setenv("FZF_SELECT", name)
+		if _, err := e.run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	requests := len(e.srv.Requests())
+
+	out, err := e.run("recent", "--print")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "github.com/acme/repo001\ngithub.com/octocat/dotfiles"; out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+
+	t.Setenv("FZF_SELECT", "octocat/dotfiles")
+	out, err = e.run("recent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(e.ghqRoot, "github.com", "octocat", "dotfiles"); out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+	if n := len(e.srv.Requests()); n != requests {
+		t.Errorf("recent made %d API requests", n-requests)
+	}
+	for _, n := range []string{"0", "-1"} {
+		if _, err := e.run("recent", "-n", n); err == nil || !strings.Contains(err.Error(), "invalid -n") {
+			t.Errorf("recent -n %s: err = %v, want the flag to be rejected", n, err)
+		}
+	}
+
+	history, err := os.ReadFile(e.cachePath("_history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(history), `"action":"clone"`) || !strings.Contains(string(history), `"action":"open"`) {
+		t.Errorf("history does not record the actions: %s", history)
+	}
+}
+
+func TestRunPin(t *testing.T) {
+	e := setup(t)
+	out, err := e.run("pin", "github.com/octocat/dotfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Pinned github.com/octocat/dotfiles"; out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run("-sort", "name"); err != nil {
+		t.Fatal(err)
+	}
+	first, _, _ := strings.Cut(e.selectorInput(), "\n")
+	if !strings.Contains(first, "octocat/dotfiles") || !strings.Contains(first, "📌") {
+		t.Errorf("first line = %q, want pinned octocat/dotfiles", first)
+	}
+
+	// fzf のバインドは選択中の行のキーを渡す
+	if out, err = e.run("pin", "--toggle", "--", cmd.LineKey(first)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Unpinned github.com/octocat/dotfiles"; out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+	if out, err = e.run("unpin", "github.com/octocat/dotfiles"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "github.com/octocat/dotfiles is not pinned"; out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	e := setup(t)
+	if _, err := e.run("config", "set", "sort", "name"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.run("config", "set", "orgs.deny", "tools, legacy"); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := e.run("config", "get", "orgs.deny"); out != "tools,legacy" {
+		t.Errorf("orgs.deny = %q, want tools,legacy", out)
+	}
+	out, err := e.run("config", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(out, "\n"); !slices.Contains(lines, "sort=name") || !slices.Contains(lines, "cache_ttl=") {
+		t.Errorf("list = %q", out)
+	}
+
+	if _, err := e.run("config", "set", "cache_ttl", "soon"); err == nil || !strings.Contains(err.Error(), "cache_ttl") {
+		t.Errorf("err = %v, want an error naming cache_ttl", err)
+	}
+	if _, err := e.run("config", "get", "colour"); err == nil {
+		t.Error("unknown key was accepted")
+	}
+	e.writeConfig("sort: newest\n")
+	if _, err := e.run(); err == nil || !strings.Contains(err.Error(), `invalid sort "newest"`) {
+		t.Errorf("err = %v, want an error naming sort", err)
+	}
+}
+
+func TestRunConfigPrecedence(t *testing.T) {
+	e := setup(t)
+	e.writeConfig("sort: stars\n")
+	t.Setenv("GH_OTUI_SORT", "name")
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	names := func() []string {
+		var names []string
+		for _, line := range strings.Split(strings.TrimSpace(e.selectorInput()), "\n") {
+			repo, err := models.ParsePath(cmd.LineKey(line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, repo.Name)
+		}
+		return names
+	}
+
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(), []string{"dotfiles", "repo000", "repo001", "repo002"}; !slices.Equal(got, want) {
+		t.Errorf("listed %q, want %q sorted by name from the environment", got, want)
+	}
+	if _, err := e.run("-sort", "org"); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(); got[0] != "repo000" {
+		t.Errorf("listed %q, want acme first sorted by the flag", got)
+	}
+	// 環境変数の値は設定ファイルに保存しない
+	if out, _ := e.run("config", "get", "sort"); out != "stars" {
+		t.Errorf("sort = %q, want stars", out)
+	}
+
+	t.Setenv("GH_OTUI_SORT", "random")
+	if _, err := e.run(); err == nil || !strings.Contains(err.Error(), "GH_OTUI_SORT") {
+		t.Errorf("err = %v, want an error naming GH_OTUI_SORT", err)
+	}
+}
+
+func TestRunAction(t *testing.T) {
+	e := setup(t)
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	e.writeConfig("actions:\n  - key: ctrl-o\n    run: echo \"$GH_OTUI_REPO $GH_OTUI_URL [$GH_OTUI_PATH]\"\n")
+	clone := filepath.Join(e.ghqRoot, "github.com", "acme", "repo000")
+	out, err := e.run("action", "ctrl-o", "--", "github.com/acme/repo000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "github.com/acme/repo000 https://github.com/acme/repo000 [" + clone + "]"; out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+
+	// API から取得していないリポジトリの URL は推測しない
+	local := filepath.Join(e.ghqRoot, "git.example.com", "tools", "cli")
+	if err := os.MkdirAll(filepath.Join(local, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if out, err = e.run("action", "ctrl-o", "--", "git.example.com/tools/cli"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "git.example.com/tools/cli  [" + local + "]"; out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+	if _, err := e.run("action", "ctrl-x", "--", "github.com/acme/repo000"); err == nil {
+		t.Error("unbound key was accepted")
+	}
+}
+
+func TestRunDoctor(t *testing.T) {
+	e := setup(t)
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := e.run("doctor")
+	if err != nil {
+		t.Fatalf("doctor failed: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		"✓ gh: gh version 2.62.0 (stub)",
+		"✓ fzf: 0.56.3 (stub)",
+		"✓ github.com: logged in as octocat (scopes: repo, read:org)",
+		"✓ " + e.ghqRoot + " (primary)",
+		"No problems found.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	e.srv.SetScopes("repo")
+	if err := os.WriteFile(e.cachePath("github.com", "acme.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = e.run("doctor")
+	if err == nil {
+		t.Fatal("doctor passed with a broken cache file")
+	}
+	for _, want := range []string{
+		"! github.com: the token lacks read:org",
+		"→ run `gh auth refresh --hostname github.com --scopes read:org`",
+		"✗ " + e.cachePath("github.com", "acme.json") + ": invalid JSON",
+		"→ run `gh otui cache clear --host github.com --org acme` to fetch it again",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunCacheStatus(t *testing.T) {
+	e := setup(t)
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := e.run("cache", "status", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s cache.Summary
+	if err := json.Unmarshal([]byte(out), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Stale || s.LastUpdated.IsZero() || s.Size == 0 {
+		t.Errorf("summary = %+v, want a fresh cache", s)
+	}
+	var groups []string
+	for _, g := range s.Groups {
+		groups = append(groups, fmt.Sprintf("%s/%s:%d", g.Host, g.Org, g.Repositories))
+	}
+	if want := []string{"github.com/acme:3", "github.com/octocat:1"}; !slices.Equal(groups, want) {
+		t.Errorf("groups = %q, want %q", groups, want)
+	}
+
+	if err := os.WriteFile(e.cachePath("github.com", "acme.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GH_OTUI_CACHE_TTL", "1ns")
+	out, err = e.run("cache", "status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"stale (TTL 1ns)", "HOST", "broken", "✗ github.com/acme: "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunCacheClearSelectively(t *testing.T) {
+	e := setup(t)
+	t.Setenv("FZF_SELECT", "acme/repo001")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := e.run("cache", "clear", "--org", "ACME")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Cleared github.com/acme.json"; out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+	out, err = e.run("cache", "status", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s cache.Summary
+	if err := json.Unmarshal([]byte(out), &s); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Stale || len(s.Groups) != 1 || s.Groups[0].Org != "octocat" {
+		t.Errorf("summary = %+v, want only octocat left and a stale cache", s)
+	}
+	if _, err := os.Stat(e.cachePath("_history.json")); err != nil {
+		t.Errorf("history was removed: %v", err)
+	}
+
+	if _, err := e.run("cache", "clear", "--history", "--metadata"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"_history.json", "_md.json"} {
+		if _, err := os.Stat(e.cachePath(name)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(e.cachePath("github.com", "octocat.json")); err != nil {
+		t.Errorf("group was removed: %v", err)
+	}
+
+	if _, err := e.run("cache", "clear", "--host", "ghe.example.com"); err == nil {
+		t.Error("clearing an uncached host succeeded")
+	}
+	if _, err := e.run("cache", "clear", "--host", ".."); err == nil {
+		t.Error("clearing outside the cache was allowed")
+	}
+	if _, err := e.run("cache", "clear", "--host", "github.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(e.cachePath("github.com")); !os.IsNotExist(err) {
+		t.Errorf("host directory was not removed: %v", err)
+	}
+}
+
+func TestRunOffline(t *testing.T) {
+	e := setup(t)
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GH_OTUI_CACHE_TTL", "1ns")
+	requests := len(e.srv.Requests())
+	gitCalls := len(e.gitCalls())
+
+	out, err := e.run("-offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(e.ghqRoot, "github.com", "acme", "repo000"); out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+
+	t.Setenv("FZF_SELECT", "acme/repo001")
+	_, err = e.run("-offline")
+	if err == nil || !strings.Contains(err.Error(), "github.com/acme/repo001 is not cloned: cannot clone while offline") {
+		t.Errorf("err = %v, want the clone to be refused", err)
+	}
+	if _, err := e.run("-offline", "search", "dotfiles"); err == nil {
+		t.Error("searched while offline")
+	}
+	if _, err := e.run("-offline", "add-owner", "golang"); err == nil {
+		t.Error("added an owner while offline")
+	}
+
+	// 接続できなければ自動でオフラインになる
+	reachable = func(context.Context, []string) bool { return false }
+	var stderr bytes.Buffer
+	err = run(context.Background(), []string{"gh-otui"}, io.Discard, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("err = %v, want the clone to be refused", err)
+	}
+	if !strings.Contains(stderr.String(), "No network connection") {
+		t.Errorf("stderr = %q, want the offline notice", stderr.String())
+	}
+
+	if got := len(e.srv.Requests()); got != requests {
+		t.Errorf("%d API requests were made offline", got-requests)
+	}
+	for _, call := range e.gitCalls()[gitCalls:] {
+		if strings.HasPrefix(call, "clone") {
+			t.Errorf("git %s was run offline", call)
+		}
+	}
+
+	// 検出を止めれば API に届かないと判定されても通常どおり動く
+	t.Setenv("GH_OTUI_NO_OFFLINE_DETECTION", "true")
+	if out, err = e.run(); err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(e.ghqRoot, "github.com", "acme", "repo001"); out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+}
+
+func TestReachThroughProxy(t *testing.T) {
+	api := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer api.Close()
+	var mu sync.Mutex
+	var connects []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		mu.Lock()
+		connects = append(connects, r.Host)
+		mu.Unlock()
+		// どのホストへの接続も API のサーバーに繋ぐ
+		upstream, err := net.Dial("tcp", api.Listener.Addr().String())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// .invalid は名前解決できないので直接は繋がらない
+	hosts := []string{"gh-otui.invalid"}
+	direct := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	defer direct.CloseIdleConnections()
+	if reach(context.Background(), direct, hosts) {
+		t.Error("reached an unresolvable host without the proxy")
+	}
+	proxied := &http.Transport{Proxy: http.ProxyURL(proxyURL), TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	defer proxied.CloseIdleConnections()
+	if !reach(context.Background(), proxied, hosts) {
+		t.Error("did not reach the host through the proxy")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"gh-otui.invalid:443"}; !slices.Equal(connects, want) {
+		t.Errorf("proxy got CONNECT %q, want %q", connects, want)
+	}
+}
+
+func TestRunOfflineWithFreshCache(t *testing.T) {
+	e := setup(t)
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	gitCalls := len(e.gitCalls())
+
+	// キャッシュが新しくても、クローンする前に接続を確認する
+	reachable = func(context.Context, []string) bool { return false }
+	t.Setenv("FZF_SELECT", "acme/repo001")
+	var stderr bytes.Buffer
+	err := run(context.Background(), []string{"gh-otui"}, io.Discard, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "github.com/acme/repo001 is not cloned: cannot clone while offline") {
+		t.Errorf("err = %v, want the clone to be refused", err)
+	}
+	if !strings.Contains(stderr.String(), "No network connection") {
+		t.Errorf("stderr = %q, want the offline notice", stderr.String())
+	}
+	for _, call := range e.gitCalls()[gitCalls:] {
+		if strings.HasPrefix(call, "clone") {
+			t.Errorf("git %s was run offline", call)
+		}
+	}
+
+	// クローン済みのリポジトリは接続を確認せずに開ける
+	reachable = func(context.Context, []string) bool {
+		t.Error("checked the network to open a clone")
+		return false
+	}
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunOfflineWithoutCache(t *testing.T) {
+	e := setup(t)
+	if err := os.MkdirAll(filepath.Join(e.ghqRoot, "github.com", "acme", "local", ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FZF_SELECT", "acme/local")
+	out, err := e.run("-offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(e.ghqRoot, "github.com", "acme", "local"); out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+	if got := e.srv.Requests(); len(got) != 0 {
+		t.Errorf("requests = %q, want none", got)
+	}
+}
+
+func TestRunSkipsFailingHost(t *testing.T) {
+	e := setup(t)
+	ghe := fakegithub.New("ghe.example.com")
+	t.Cleanup(ghe.Close)
+	ghe.AddOrganization("corp", ghe.Repositories("corp", 2)...)
+	ghe.RevokeToken()
+	t.Setenv("GH_HOST", "ghe.example.com")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "token")
+	newClient = func(host string) (*github.Client, error) {
+		return github.NewClient(api.ClientOptions{
+			Host:         host,
+			AuthToken:    "token",
+			Transport:    fakegithub.Transport(e.srv, ghe),
+			LogIgnoreEnv: true,
+		})
+	}
+
+	// 失敗したホストを飛ばして他のホストは取得する
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(e.cachePath("github.com", "acme.json")); err != nil {
+		t.Errorf("repositories of the working host were not cached: %v", err)
+	}
+	if _, err := os.Stat(e.cachePath("ghe.example.com")); !os.IsNotExist(err) {
+		t.Errorf("failing host was cached: %v", err)
+	}
+
+	out, err := e.run("cache", "status", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s cache.Summary
+	if err := json.Unmarshal([]byte(out), &s); err != nil {
+		t.Fatal(err)
+	}
+	if msg := s.HostErrors["ghe.example.com"]; !strings.Contains(msg, "401") || !strings.Contains(msg, "gh auth login --hostname ghe.example.com") {
+		t.Errorf("host errors = %v, want the 401 of ghe.example.com", s.HostErrors)
+	}
+	if _, ok := s.HostErrors["github.com"]; ok {
+		t.Errorf("host errors = %v, want none for github.com", s.HostErrors)
+	}
+
+	out, err = e.run("doctor")
+	if err == nil {
+		t.Error("doctor found no problem")
+	}
+	if !strings.Contains(out, "the last refresh of ghe.example.com failed") {
+		t.Errorf("doctor does not report the failed refresh:\n%s", out)
+	}
+}
+
+func TestRunWarnsAboutTruncatedListing(t *testing.T) {
+	e := setup(t)
+	e.srv.AddOrganization("big", e.srv.Repositories("big", 250)...)
+	e.writeConfig("max_pages: 2\n")
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 記録された打ち切りは以後の実行でも警告する
+	var stderr bytes.Buffer
+	if err := run(context.Background(), []string{"gh-otui"}, io.Discard, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if want := "warning: github.com/orgs/big/repos has 3 pages but only 2 were cached"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestRunConfigRepairsInvalidFile(t *testing.T) {
+	e := setup(t)
+	e.writeConfig("sort: newest\ntheme: neon\n")
+	if _, err := e.run(); err == nil || !strings.Contains(err.Error(), `invalid sort "newest"`) {
+		t.Fatalf("err = %v, want the invalid sort", err)
+	}
+
+	// 他のキーが不正なままでも1つずつ直せる
+	if _, err := e.run("config", "set", "sort", "name"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.run("config", "set", "theme", "neon"); err == nil {
+		t.Error("invalid theme was accepted")
+	}
+	if _, err := e.run("config", "set", "theme", ""); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := e.run("config", "get", "sort"); out != "name" {
+		t.Errorf("sort = %q, want name", out)
+	}
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run(); err != nil {
+		t.Errorf("repaired config is still rejected: %v", err)
+	}
+}
+
+func TestRunConfigUnknownKey(t *testing.T) {
+	e := setup(t)
+	e.writeConfig("sort: name\nselctor: fzf\nactions:\n  - key: ctrl-o\n    command: gh browse\n")
+	_, err := e.run()
+	if err == nil {
+		t.Fatal("config with unknown keys was accepted")
+	}
+	for _, want := range []string{`unknown key "selctor" on line 2`, `unknown key "command" on line 5`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestRunOrgFlagNarrowsConfig(t *testing.T) {
+	e := setup(t)
+	e.srv.AddOrganization("tools", e.srv.Repositories("tools", 1)...)
+	e.writeConfig("orgs:\n  allow: [acme, octocat]\n")
+	owners := func() []string {
+		var owners []string
+		for _, line := range strings.Split(strings.TrimSpace(e.selectorInput()), "\n") {
+			repo, err := models.ParsePath(cmd.LineKey(line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Contains(owners, repo.OrgName) {
+				owners = append(owners, repo.OrgName)
+			}
+		}
+		slices.Sort(owners)
+		return owners
+	}
+
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run("-sort", "name"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := owners(), []string{"acme", "octocat"}; !slices.Equal(got, want) {
+		t.Errorf("listed %q, want %q", got, want)
+	}
+	if _, err := e.run("-sort", "name", "-org", "acme", "-org", "tools"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := owners(), []string{"acme"}; !slices.Equal(got, want) {
+		t.Errorf("listed %q with -org, want %q", got, want)
+	}
+	if _, err := e.run("-sort", "name", "-exclude-org", "octocat"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := owners(), []string{"acme"}; !slices.Equal(got, want) {
+		t.Errorf("listed %q with -exclude-org, want %q", got, want)
+	}
+}
+
+func TestRunAddOwnerPageLimit(t *testing.T) {
+	e := setup(t)
+	e.srv.AddUser("golang", e.srv.Repositories("golang", 250)...)
+	out, err := e.run("-max-pages", "2", "add-owner", "golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Added github.com/golang (200 repositories)"; out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+	out, err = e.run("cache", "status", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s cache.Summary
+	if err := json.Unmarshal([]byte(out), &s); err != nil {
+		t.Fatal(err)
+	}
+	want := []cache.Truncation{{Host: "github.com", Listing: "users/golang/repos", Fetched: 2, LastPage: 3}}
+	if !slices.Equal(s.Truncations, want) {
+		t.Errorf("truncations = %v, want %v", s.Truncations, want)
+	}
+	// -max-pages はその実行だけに適用する
+	if out, _ := e.run("config", "get", "max_pages"); out != "" {
+		t.Errorf("max_pages = %q, want it unset", out)
+	}
+}
+
+func TestRunPrunesUnstarredOwners(t *testing.T) {
+	e := setup(t)
+	e.writeConfig("sources: [starred]\n")
+	e.srv.SetListing("user/starred", e.srv.Repository("hubot", "tools"), e.srv.Repository("acme", "repo000"))
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(e.cachePath("github.com", "hubot.starred.json")); err != nil {
+		t.Fatalf("starred repositories were not cached: %v", err)
+	}
+
+	e.srv.SetListing("user/starred", e.srv.Repository("acme", "repo000"))
+	if _, err := e.run("cache", "clear", "--metadata"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(e.cachePath("github.com", "hubot.starred.json")); !os.IsNotExist(err) {
+		t.Errorf("unstarred owner is still cached: %v", err)
+	}
+	if _, err := os.Stat(e.cachePath("github.com", "acme.starred.json")); err != nil {
+		t.Errorf("starred owner was pruned: %v", err)
+	}
+	if strings.Contains(e.selectorInput(), "hubot/tools") {
+		t.Errorf("unstarred repository is still listed:\n%s", e.selectorInput())
+	}
+}
+
+func TestRunPrunesDisabledSource(t *testing.T) {
+	e := setup(t)
+	e.writeConfig("sources: [starred]\n")
+	e.srv.SetListing("user/starred", e.srv.Repository("hubot", "tools"))
+	t.Setenv("FZF_SELECT", "acme/repo000")
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(e.selectorInput(), "hubot/tools [starred]") {
+		t.Fatalf("starred repository is not listed:\n%s", e.selectorInput())
+	}
+
+	e.writeConfig("")
+	if _, err := e.run("cache", "clear", "--metadata"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(e.cachePath("github.com", "hubot.starred.json")); !os.IsNotExist(err) {
+		t.Errorf("group of the disabled source is still cached: %v", err)
+	}
+	if strings.Contains(e.selectorInput(), "hubot/tools") {
+		t.Errorf("repository of the disabled source is still listed:\n%s", e.selectorInput())
+	}
+}
+
+func TestRunHostlessLineFormat(t *testing.T) {
+	e := setup(t)
+	e.writeConfig("line_format: '{{.Mark}} {{.Namespace}}/{{.Name}}'\n")
+	// 別ホストの同名リポジトリも同じ行で表示される
+	clone := filepath.Join(e.ghqRoot, "git.example.com", "acme", "repo001")
+	if err := os.MkdirAll(filepath.Join(clone, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FZF_SELECT", "git.example.com/acme/repo001")
+
+	out, err := e.run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != clone {
+		t.Errorf("printed %q, want %q", out, clone)
+	}
+	in := e.selectorInput()
+	for _, want := range []string{"github.com/acme/repo001\t  acme/repo001\n", "git.example.com/acme/repo001\t✓ acme/repo001\n"} {
+		if !strings.Contains(in, want) {
+			t.Errorf("selector input does not contain %q:\n%s", want, in)
+		}
+	}
+}
+
+func TestRunSearch(t *testing.T) {
+	e := setup(t)
+	e.srv.AddUser("golang", e.srv.Repository("golang", "go"))
+	e.writeConfig("line_format: '{{.Namespace}}/{{.Name}}'\n")
+
+	// リロードで読み込まれる行にもキーが付く
+	out, err := e.run("search", "--print", "--", "golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, _ := strings.Cut(out, "\n")
+	if want := "github.com/golang/go\tgolang/go"; first != want {
+		t.Errorf("first line = %q, want %q", first, want)
+	}
+
+	// 検索結果と重なってもピン留めとクローン済みの印は残る
+	if err := os.MkdirAll(filepath.Join(e.ghqRoot, "github.com", "golang", "go", ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	e.writeConfig("line_format: '{{.Mark}} {{.Namespace}}/{{.Name}}{{if .Pinned}} 📌{{end}}'\npins: [github.com/golang/go]\n")
+	out, err = e.run("search", "--print", "--", "golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, _ = strings.Cut(out, "\n")
+	if want := "github.com/golang/go\t✓ golang/go 📌"; first != want {
+		t.Errorf("first line = %q, want %q", first, want)
+	}
+
+	t.Setenv("FZF_SELECT", "golang/go")
+	out, err = e.run("search", "golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(e.ghqRoot, "github.com", "golang", "go"); out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,242 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultLineFormat is the template of the selector lines unless configured
+// otherwise. Tabs separate columns, which are aligned across lines.
+const DefaultLineFormat = `{{style "mark" .Mark}} {{style "host" .Host}}/{{style "owner" .Namespace}}/{{style (nameStyle .) .Name}}` +
+	`{{with .Tags}} {{style "tags" .}}{{end}}{{if .Pinned}} {{style "pin" "📌"}}{{end}}{{with icons .}} {{.}}{{end}}` +
+	`{{with .Status}}	{{style "status" .}}{{end}}`
+
+// LineFormat renders repositories as selector lines with a text/template.
+// The template is executed with a Repository and may use the functions in
+// lineFuncs, e.g. {{color "cyan" .Language}} or {{ago .PushedAt}}, and
+// {{style "host" .Host}} to color a part of the line with the theme.
+type LineFormat struct {
+	tmpl  *template.Template
+	theme Theme
+	// colors is false when NO_COLOR is set.
+	colors bool
+}
+
+// NewLineFormat parses text as a line template colored with the named theme.
+// An empty text means DefaultLineFormat and an empty theme leaves the lines
+// plain, unless the template colors them itself.
+func NewLineFormat(text, theme string) (*LineFormat, error) {
+	if text == "" {
+		text = DefaultLineFormat
+	}
+	f := &LineFormat{colors: os.Getenv("NO_COLOR") == ""}
+	if theme != "" {
+		t, ok := Themes[theme]
+		if !ok {
+			return nil, fmt.Errorf("unknown theme %q", theme)
+		}
+		f.theme = t
+	}
+	funcs := template.FuncMap{
+		"color": f.color,
+		"style": func(role string, text any) (string, error) {
+			return f.color(f.theme.Styles[role], text)
+		},
+		"nameStyle": func(r Repository) string {
+			if r.Archived {
+				return "archived"
+			}
+			return "name"
+		},
+		"icons": f.icons,
+	}
+	tmpl, err := template.New("line").Funcs(lineFuncs).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	f.tmpl = tmpl
+	return f, nil
+}
+
+// Lines renders repos, one line each, with the tab separated columns aligned.
+func (f *LineFormat) Lines(repos []Repository) ([]string, error) {
+	rows := make([][]string, len(repos))
+	var sb strings.Builder
+	for i, repo := range repos {
+		sb.Reset()
+		if err := f.tmpl.Execute(&sb, repo); err != nil {
+			return nil, fmt.Errorf("failed to format %s/%s: %w", repo.Host, repo.FullName(), err)
+		}
+		rows[i] = strings.Split(strings.ReplaceAll(sb.String(), "\n", " "), "\t")
+	}
+	return alignColumns(rows), nil
+}
+
+// color wraps text in the escape sequences of spec, a color name such as
+// "red" or "bold", or a comma separated list of them. An empty spec or
+// NO_COLOR leaves text as is.
+func (f *LineFormat) color(spec string, text any) (string, error) {
+	s := fmt.Sprint(text)
+	if s == "" || spec == "" {
+		return s, nil
+	}
+	var codes []string
+	for _, n := range strings.Split(spec, ",") {
+		code, ok := colorCodes[strings.TrimSpace(n)]
+		if !ok {
+			return "", fmt.Errorf("unknown color %q", n)
+		}
+		codes = append(codes, code)
+	}
+	if !f.colors {
+		return s, nil
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + s + "\x1b[0m", nil
+}
+
+// icons returns the icons of the theme that apply to r, such as a lock for
+// private repositories.
+func (f *LineFormat) icons(r Repository) (string, error) {
+	var icons []string
+	for _, role := range []string{"private", "archived"} {
+		icon := f.theme.Icons[role]
+		if icon == "" || (role == "private" && !r.Private) || (role == "archived" && !r.Archived) {
+			continue
+		}
+		s, err := f.color(f.theme.Styles[role], icon)
+		if err != nil {
+			return "", err
+		}
+		icons = append(icons, s)
+	}
+	return strings.Join(icons, " "), nil
+}
+
+// alignColumns pads every column but the last of each row to the widest cell
+// of the column, leaving two spaces between columns.
+func alignColumns(rows [][]string) []string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row[:len(row)-1] {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		var sb strings.Builder
+		for j, cell := range row {
+			sb.WriteString(cell)
+			if j < len(row)-1 {
+				sb.WriteString(strings.Repeat(" ", widths[j]-displayWidth(cell)+2))
+			}
+		}
+		lines[i] = strings.TrimRight(sb.String(), " ")
+	}
+	return lines
+}
+
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// StripANSI removes the color escape sequences from s.
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// HasANSI reports whether s contains color escape sequences.
+func HasANSI(s string) bool {
+	return strings.Contains(s, "\x1b[")
+}
+
+// displayWidth returns the number of terminal cells s takes, counting wide
+// characters such as CJK and emoji as two.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range StripANSI(s) {
+		width++
+		if isWide(r) {
+			width++
+		}
+	}
+	return width
+}
+
+func isWide(r rune) bool {
+	return (r >= 0x1100 && r <= 0x115f) ||
+		(r >= 0x2e80 && r <= 0xa4cf) ||
+		(r >= 0xac00 && r <= 0xd7a3) ||
+		(r >= 0xf900 && r <= 0xfaff) ||
+		(r >= 0xfe30 && r <= 0xfe4f) ||
+		(r >= 0xff00 && r <= 0xff60) ||
+		(r >= 0xffe0 && r <= 0xffe6) ||
+		(r >= 0x1f300 && r <= 0x1faff) ||
+		(r >= 0x20000 && r <= 0x3fffd)
+}
+
+// Mark returns "✓" for cloned repositories and a space otherwise.
+func (r Repository) Mark() string {
+	if r.Cloned {
+		return "✓"
+	}
+	return " "
+}
+
+// Tags returns the sources of the repository as "[starred,watching]", or ""
+// when it has none.
+func (r Repository) Tags() string {
+	if len(r.Sources) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(r.Sources, ",") + "]"
+}
+
+var colorCodes = map[string]string{
+	"black":   "30",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+	"gray":    "90",
+	"bold":    "1",
+	"dim":     "2",
+	"italic":  "3",
+	"strike":  "9",
+}
+
+var lineFuncs = template.FuncMap{
+	// ago returns how long ago t was, e.g. "3d" or "5mo".
+	"ago": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		d := time.Since(t)
+		switch {
+		case d < time.Hour:
+			return fmt.Sprintf("%dm", int(d.Minutes()))
+		case d < 24*time.Hour:
+			return fmt.Sprintf("%dh", int(d.Hours()))
+		case d < 30*24*time.Hour:
+			return fmt.Sprintf("%dd", int(d.Hours()/24))
+		case d < 365*24*time.Hour:
+			return fmt.Sprintf("%dmo", int(d.Hours()/24/30))
+		}
+		return fmt.Sprintf("%dy", int(d.Hours()/24/365))
+	},
+	// truncate shortens s to n characters.
+	"truncate": func(n int, s string) string {
+		if utf8.RuneCountInString(s) <= n {
+			return s
+		}
+		return string([]rune(s)[:max(n-1, 0)]) + "…"
+	},
+}
@@ -2,40 +2,123 @@ package models
 
 import (
 	"fmt"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 )
 
 type Repository struct {
-	Name    string `json:"name"`
-	OrgName string
-	HtmlUrl string `json:"html_url"`
-	Host    string
-	Cloned  bool
+	Name        string `json:"name"`
+	OrgName     string
+	HtmlUrl     string `json:"html_url"`
+	Host        string
+	Cloned      bool
+	Description string    `json:"description,omitempty"`
+	Language    string    `json:"language,omitempty"`
+	Stars       int       `json:"stars,omitempty"`
+	PushedAt    time.Time `json:"pushed_at"`
+	Private     bool      `json:"private,omitempty"`
+	Archived    bool      `json:"archived,omitempty"`
+	// Subgroup is the path of the namespaces nested under the owner, such as
+	// GitLab subgroups. It is empty for GitHub repositories.
+	Subgroup string `json:"subgroup,omitempty"`
+	// Sources lists where the repository was found besides its owner's
+	// repository list, e.g. "starred" or "watching".
+	Sources []string `json:"sources,omitempty"`
+	// Status is the state of the local clone, when it was checked.
+	Status *WorkingCopy `json:"-"`
+	// Pinned repositories are listed first.
+	Pinned bool `json:"-"`
+}
+
+// WorkingCopy is the state of a local clone.
+type WorkingCopy struct {
+	Branch string `json:"branch"`
+	Dirty  bool   `json:"dirty"`
+	// Ahead is the number of commits not pushed to the upstream branch.
+	Ahead int `json:"ahead"`
+	// Behind is the number of upstream commits not merged yet.
+	Behind int `json:"behind"`
+}
+
+// String returns the status as shown in the selector, e.g. "main* ↑2 ↓1".
+func (w WorkingCopy) String() string {
+	s := w.Branch
+	if w.Dirty {
+		s += "*"
+	}
+	if w.Ahead > 0 {
+		s += fmt.Sprintf(" ↑%d", w.Ahead)
+	}
+	if w.Behind > 0 {
+		s += fmt.Sprintf(" ↓%d", w.Behind)
+	}
+	return s
 }
 
 type Organization struct {
 	Login string `json:"login"`
 }
 
+// FullName returns the path of the repository on its host, e.g. "org/name"
+// or "group/sub/name".
+func (r Repository) FullName() string {
+	return path.Join(r.OrgName, r.Subgroup, r.Name)
+}
+
+// Key identifies the repository across hosts, e.g. "github.com/org/name".
+func (r Repository) Key() string {
+	return r.Host + "/" + r.FullName()
+}
+
+// Namespace returns the owner with any nested namespaces, e.g. "org" or
+// "group/sub".
+func (r Repository) Namespace() string {
+	return path.Join(r.OrgName, r.Subgroup)
+}
+
 func (r Repository) GetClonePath(ghqRoot string) (string, error) {
-	return filepath.Join(ghqRoot, r.Host, r.OrgName, r.Name), nil
+	return filepath.Join(ghqRoot, r.Host, filepath.FromSlash(r.FullName())), nil
 }
 
 func (r Repository) GetGitURL() string {
-	return fmt.Sprintf("git@%s:%s/%s", r.Host, r.OrgName, r.Name)
+	return fmt.Sprintf("git@%s:%s", r.Host, r.FullName())
+}
+
+// CloneURL returns the URL to clone the repository with over protocol,
+// "https" or "ssh".
+func (r Repository) CloneURL(protocol string) string {
+	if protocol == "https" {
+		return fmt.Sprintf("https://%s/%s.git", r.Host, r.FullName())
+	}
+	return r.GetGitURL()
 }
 
-func (r Repository) FormattedLine() string {
-	cloneStatus := " "
-	if r.Cloned {
-		cloneStatus = "✓"
+// ParsePath parses "host/owner/name", where nested namespaces may come
+// between the owner and the name.
+func ParsePath(p string) (Repository, error) {
+	parts := strings.Split(p, "/")
+	if len(parts) < 3 || slices.Contains(parts, "") {
+		return Repository{}, fmt.Errorf("%s is not a host/owner/name path", p)
 	}
-	return fmt.Sprintf("%s %s/%s/%s", cloneStatus, r.Host, r.OrgName, r.Name)
+	return Repository{
+		Host:     parts[0],
+		OrgName:  parts[1],
+		Subgroup: strings.Join(parts[2:len(parts)-1], "/"),
+		Name:     parts[len(parts)-1],
+	}, nil
+}
+
+func (r Repository) HasSource(source string) bool {
+	return slices.Contains(r.Sources, source)
 }
 
 type RepositoryGroup struct {
 	host         string
 	organization string
+	source       string
 	repositories []Repository
 }
 
@@ -43,10 +126,45 @@ func NewRepositoryGroup(repositories ...Repository) (*RepositoryGroup, error) {
 	if len(repositories) == 0 {
 		return nil, fmt.Errorf("no repositories provided")
 	}
+	return NewOwnerRepositoryGroup(repositories[0].Host, repositories[0].OrgName, repositories...)
+}
+
+// NewOwnerRepositoryGroup creates the group of an owner's repositories. Unlike
+// NewRepositoryGroup it accepts an owner without repositories.
+func NewOwnerRepositoryGroup(host, organization string, repositories ...Repository) (*RepositoryGroup, error) {
+	if host == "" || organization == "" {
+		return nil, fmt.Errorf("host and organization cannot be empty")
+	}
+
+	g := &RepositoryGroup{
+		host:         host,
+		organization: organization,
+		repositories: make([]Repository, 0, len(repositories)),
+	}
+
+	for _, repo := range repositories {
+		if err := g.Add(repo); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// NewSourceRepositoryGroup creates a group of repositories that were listed
+// through source, such as the login user's starred repositories, rather than
+// through their owner. The repositories are tagged with source.
+func NewSourceRepositoryGroup(source string, repositories ...Repository) (*RepositoryGroup, error) {
+	if source == "" {
+		return nil, fmt.Errorf("source cannot be empty")
+	}
+	if len(repositories) == 0 {
+		return nil, fmt.Errorf("no repositories provided")
+	}
 
 	g := &RepositoryGroup{
 		host:         repositories[0].Host,
 		organization: repositories[0].OrgName,
+		source:       source,
 		repositories: make([]Repository, 0, len(repositories)),
 	}
 
@@ -66,13 +184,22 @@ func (g *RepositoryGroup) Organization() string {
 	return g.organization
 }
 
+// Source returns the source the group was listed through, or "" for groups
+// holding an owner's own repositories.
+func (g *RepositoryGroup) Source() string {
+	return g.source
+}
+
 func (g *RepositoryGroup) Add(repo Repository) error {
-	if repo.Host != g.host {
+	if !strings.EqualFold(repo.Host, g.host) {
 		return fmt.Errorf("repository host %s does not match group host %s", repo.Host, g.host)
 	}
-	if repo.OrgName != g.organization {
+	if !strings.EqualFold(repo.OrgName, g.organization) {
 		return fmt.Errorf("repository organization %s does not match group organization %s", repo.OrgName, g.organization)
 	}
+	if g.source != "" && !repo.HasSource(g.source) {
+		repo.Sources = append(slices.Clip(repo.Sources), g.source)
+	}
 	g.repositories = append(g.repositories, repo)
 	return nil
 }
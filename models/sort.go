@@ -0,0 +1,68 @@
+package models
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	SortFrecency = "frecency"
+	SortPushed   = "pushed"
+	SortName     = "name"
+	SortOrg      = "org"
+	SortStars    = "stars"
+)
+
+// SortModes are the orders the selector can list repositories in.
+var SortModes = []string{SortFrecency, SortPushed, SortName, SortOrg, SortStars}
+
+// SortRepositories orders repos by mode, pinned repositories first. Frecency
+// sorts by the scores of the lowercased repository keys. Repositories that
+// compare equal keep their order.
+func SortRepositories(repos []Repository, mode string, frecency map[string]float64) error {
+	var compare func(a, b Repository) int
+	switch mode {
+	case SortFrecency:
+		compare = func(a, b Repository) int {
+			return cmp.Compare(frecency[strings.ToLower(b.Key())], frecency[strings.ToLower(a.Key())])
+		}
+	case SortPushed:
+		compare = func(a, b Repository) int {
+			return b.PushedAt.Compare(a.PushedAt)
+		}
+	case SortName:
+		compare = func(a, b Repository) int {
+			return cmp.Or(
+				cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)),
+				cmp.Compare(strings.ToLower(a.Key()), strings.ToLower(b.Key())),
+			)
+		}
+	case SortOrg:
+		compare = func(a, b Repository) int {
+			return cmp.Or(
+				cmp.Compare(strings.ToLower(a.Namespace()), strings.ToLower(b.Namespace())),
+				cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)),
+				cmp.Compare(strings.ToLower(a.Host), strings.ToLower(b.Host)),
+			)
+		}
+	case SortStars:
+		compare = func(a, b Repository) int {
+			return cmp.Compare(b.Stars, a.Stars)
+		}
+	default:
+		return fmt.Errorf("unknown sort mode %q: must be one of %s", mode, strings.Join(SortModes, ", "))
+	}
+	slices.SortStableFunc(repos, func(a, b Repository) int {
+		// ピン留めされたリポジトリは常に先頭に置く
+		if a.Pinned != b.Pinned {
+			if a.Pinned {
+				return -1
+			}
+			return 1
+		}
+		return compare(a, b)
+	})
+	return nil
+}
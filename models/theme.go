@@ -0,0 +1,53 @@
+package models
+
+// Theme colors the parts of the default selector line. Styles map the roles
+// "mark", "host", "owner", "name", "archived", "private", "tags", "pin" and
+// "status" to color specs, and Icons map "private" and "archived" to the
+// icons shown for such repositories.
+type Theme struct {
+	Styles map[string]string
+	Icons  map[string]string
+}
+
+// Themes are the themes that can be chosen with the theme setting.
+var Themes = map[string]Theme{
+	"default": {
+		Styles: map[string]string{
+			"mark":     "green",
+			"host":     "gray",
+			"owner":    "blue",
+			"name":     "bold",
+			"archived": "gray,strike",
+			"private":  "yellow",
+			"tags":     "magenta",
+			"pin":      "red",
+			"status":   "cyan",
+		},
+		Icons: map[string]string{
+			"private":  "🔒",
+			"archived": "📦",
+		},
+	},
+	"subtle": {
+		Styles: map[string]string{
+			"mark":     "green",
+			"host":     "dim",
+			"owner":    "dim",
+			"archived": "dim,strike",
+			"tags":     "dim",
+			"status":   "dim",
+		},
+	},
+	"mono": {
+		Styles: map[string]string{
+			"host":     "dim",
+			"name":     "bold",
+			"archived": "dim,strike",
+			"status":   "italic",
+		},
+		Icons: map[string]string{
+			"private":  "🔒",
+			"archived": "📦",
+		},
+	},
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/n3xem/gh-otui/config"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// reachTimeout bounds the connectivity check, which delays the selector when
+// there is no network.
+const reachTimeout = 1500 * time.Millisecond
+
+// reachable reports whether the API of any of hosts can be reached through
+// the transport of the API clients, which honors HTTPS_PROXY. Tests replace
+// it.
+var reachable = func(ctx context.Context, hosts []string) bool {
+	return reach(ctx, http.DefaultTransport, hosts)
+}
+
+// reach reports whether the API of any of hosts answers a request sent
+// through rt, or true when there are no hosts to check. Any response counts,
+// even an error.
+func reach(ctx context.Context, rt http.RoundTripper, hosts []string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(ctx, reachTimeout)
+	defer cancel()
+	p := pool.NewWithResults[bool]()
+	for _, host := range hosts {
+		p.Go(func() bool {
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://"+apiHost(host)+"/", nil)
+			if err != nil {
+				return false
+			}
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				return false
+			}
+			resp.Body.Close()
+			// 1つ繋がれば十分
+			cancel()
+			return true
+		})
+	}
+	return slices.Contains(p.Wait(), true)
+}
+
+// apiHost returns the host serving the REST API of host.
+func apiHost(host string) string {
+	if host == "github.com" || strings.HasSuffix(host, ".ghe.com") {
+		return "api." + host
+	}
+	return host
+}
+
+// apiHosts returns the hosts gh-otui would call.
+func apiHosts(cfg *config.Config) []string {
+	return slices.DeleteFunc(auth.KnownHosts(), func(host string) bool {
+		return !cfg.AllowHost(host)
+	})
+}
+
+// detectOffline switches to offline mode unless the API can be reached or the
+// detection is turned off. It reports whether it did, so that the caller can
+// tell the user why the list may be out of date.
+func detectOffline(ctx context.Context, opts *options, cfg *config.Config) bool {
+	if opts.offline || opts.reachChecked || cfg.NoOfflineDetection {
+		return false
+	}
+	opts.reachChecked = true
+	if reachable(ctx, apiHosts(cfg)) {
+		return false
+	}
+	opts.offline = true
+	return true
+}
+
+const offlineNotice = "No network connection: working offline with the cached and local repositories"
+
+func offlineError(what string) error {
+	return fmt.Errorf("cannot %s while offline", what)
+}
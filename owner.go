@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/n3xem/gh-otui/cache"
+	"github.com/n3xem/gh-otui/config"
+	"github.com/n3xem/gh-otui/github"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// addOwner adds organizations or users the login user is not a member of to
+// the config and fetches at most maxPages pages of their repositories into
+// the cache.
+func addOwner(ctx context.Context, cfg *config.Config, maxPages int, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("gh otui add-owner", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	host := fs.String("hostname", "", "`host` the owners belong to (default: gh's default host)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gh otui add-owner [--hostname host] <owner>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no owner given")
+	}
+	if *host == "" {
+		*host, _ = auth.DefaultHost()
+	}
+
+	client, err := newClient(*host)
+	if err != nil {
+		return err
+	}
+	client.SetMaxPages(maxPages)
+	owners := make([]*github.OwnerOrganization, 0, fs.NArg())
+	for _, name := range fs.Args() {
+		owner, err := github.NewOwner(ctx, name, client)
+		if err != nil {
+			return err
+		}
+		if !cfg.AddOwner(*host, owner.Name()) {
+			fmt.Fprintf(stdout, "%s/%s is already added\n", *host, owner.Name())
+			continue
+		}
+		owners = append(owners, owner)
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+	if err := config.Save(cfg); err != nil {
+		return err
+	}
+
+	p := pool.NewWithResults[string]().WithErrors().WithContext(ctx)
+	for _, owner := range owners {
+		p.Go(func(ctx context.Context) (string, error) {
+			g, err := fetchOwnerRepositories(ctx, owner)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Added %s/%s (%d repositories)", *host, owner.Name(), len(g.Repositories())), nil
+		})
+	}
+	var added []string
+	err = loading("Fetching repositories...", func() error {
+		a, err := p.Wait()
+		added = a
+		return err
+	})
+	for _, msg := range added {
+		fmt.Fprintln(stdout, msg)
+	}
+	var truncations []cache.Truncation
+	for _, t := range client.Truncations() {
+		fmt.Fprintf(stderr, "warning: %s (raise max_pages to fetch more)\n", t)
+		truncations = append(truncations, cache.Truncation(t))
+	}
+	if len(truncations) > 0 {
+		if err := cache.AddTruncations(ctx, truncations); err != nil {
+			fmt.Fprintf(stderr, "warning: failed to record the truncation: %v\n", err)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("owners were added but their repositories could not be fetched yet: %w", err)
+	}
+	return nil
+}
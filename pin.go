@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/n3xem/gh-otui/cmd"
+	"github.com/n3xem/gh-otui/config"
+	"github.com/n3xem/gh-otui/models"
+)
+
+// pin adds repositories to the pins in the config, or removes them from it
+// when add is false. With --toggle each repository is pinned if it is not
+// pinned yet and unpinned otherwise, which is what the fzf binding runs with
+// the key of the current line.
+func pin(cfg *config.Config, add bool, args []string, stdout, stderr io.Writer) error {
+	name := cmdUnpin
+	if add {
+		name = cmdPin
+	}
+	fs := flag.NewFlagSet("gh otui "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	toggle := fs.Bool("toggle", false, "unpin repositories that are already pinned")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gh otui %s <host/owner/name>...\n", name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no repository given")
+	}
+
+	for _, arg := range fs.Args() {
+		repo, err := models.ParsePath(cmd.LineKey(arg))
+		if err != nil {
+			return err
+		}
+		key := repo.Key()
+		if *toggle {
+			add = !cfg.IsPinned(key)
+		}
+		switch {
+		case add && cfg.Pin(key):
+			fmt.Fprintf(stdout, "Pinned %s\n", key)
+		case add:
+			fmt.Fprintf(stdout, "%s is already pinned\n", key)
+		case cfg.Unpin(key):
+			fmt.Fprintf(stdout, "Unpinned %s\n", key)
+		default:
+			fmt.Fprintf(stdout, "%s is not pinned\n", key)
+		}
+	}
+	return config.Save(cfg)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/n3xem/gh-otui/cache"
+	"github.com/n3xem/gh-otui/cmd"
+	"github.com/n3xem/gh-otui/config"
+	"github.com/n3xem/gh-otui/models"
+)
+
+// recent lets the user pick one of the last selected repositories from the
+// history alone, without loading the cache or calling the API.
+func recent(ctx context.Context, opts *options, cfg *config.Config, ws cmd.Workspace, args []string) error {
+	fs := flag.NewFlagSet("gh otui recent", flag.ContinueOnError)
+	fs.SetOutput(opts.stderr)
+	n := fs.Int("n", 20, "list the last `n` repositories")
+	printLines := fs.Bool("print", false, "print the repositories instead of running the selector")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gh otui recent [-n count] [--print]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *n < 1 {
+		return fmt.Errorf("invalid -n %d: must be at least 1", *n)
+	}
+
+	history, err := cache.LoadHistory(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load history: %w", err)
+	}
+	var repos []models.Repository
+	for _, v := range history.Recent(*n) {
+		repo, err := models.ParsePath(v.Key)
+		if err != nil {
+			continue
+		}
+		_, repo.Cloned = cmd.FindClone(repo, ws.Roots())
+		repos = append(repos, repo)
+	}
+	if len(repos) == 0 {
+		return fmt.Errorf("no repositories have been selected yet")
+	}
+
+	if *printLines {
+		for _, repo := range repos {
+			fmt.Fprintln(opts.stdout, repo.Key())
+		}
+		return nil
+	}
+
+	selectorOpts, err := selectorOptions(opts, cfg)
+	if err != nil {
+		return err
+	}
+	selected, err := cmd.Select(ctx, opts.runner, repos, selectorOpts)
+	if err != nil {
+		if errors.Is(err, cmd.ErrRepositoryNotSelected) {
+			return nil
+		}
+		return fmt.Errorf("error selecting repository: %w", err)
+	}
+	return openRepository(ctx, opts, cfg, *selected, ws)
+}
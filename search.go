@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/n3xem/gh-otui/cmd"
+	"github.com/n3xem/gh-otui/config"
+	"github.com/n3xem/gh-otui/github"
+	"github.com/n3xem/gh-otui/models"
+	"github.com/sourcegraph/conc/pool"
+)
+
+// search looks query up on every allowed host and lets the user pick one of
+// the results or of the usual repositories. With --print the merged list is
+// written to stdout instead as selector lines, which is what the fzf reload
+// binding runs.
+func search(ctx context.Context, opts *options, cfg *config.Config, ws cmd.Workspace, args []string) error {
+	fs := flag.NewFlagSet("gh otui search", flag.ContinueOnError)
+	fs.SetOutput(opts.stderr)
+	printLines := fs.Bool("print", false, "print the selector lines instead of running the selector")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gh otui search [--print] <query>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if query == "" && !*printLines {
+		fs.Usage()
+		return fmt.Errorf("no query given")
+	}
+
+	var found []models.Repository
+	if query != "" {
+		detectOffline(ctx, opts, cfg)
+	}
+	// リロードではオフラインでも手元のリポジトリを表示する
+	if query != "" && opts.offline && !*printLines {
+		return offlineError("search GitHub")
+	}
+	if query != "" && !opts.offline {
+		var err error
+		found, err = searchRepositories(ctx, cfg, query)
+		if err != nil {
+			if len(found) == 0 {
+				return err
+			}
+			// 出力はfzfに読み込まれるので警告は表示しない
+			if !*printLines {
+				fmt.Fprintln(opts.stderr, err)
+			}
+		}
+	}
+
+	repos, err := listRepositories(ctx, opts, cfg, ws)
+	if err != nil {
+		return err
+	}
+	// 検索結果を先頭に表示する
+	repos = filterRepositories(opts, cfg, append(found, repos...))
+
+	if *printLines {
+		format, err := cfg.Format()
+		if err != nil {
+			return err
+		}
+		lines, err := cmd.SelectorLines(repos, format)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			fmt.Fprintln(opts.stdout, line)
+		}
+		return nil
+	}
+	return selectRepository(ctx, opts, cfg, repos, ws)
+}
+
+func searchRepositories(ctx context.Context, cfg *config.Config, query string) ([]models.Repository, error) {
+	hosts := slices.DeleteFunc(auth.KnownHosts(), func(host string) bool {
+		return !cfg.AllowHost(host)
+	})
+	p := pool.NewWithResults[[]models.Repository]().WithErrors().WithContext(ctx)
+	for _, host := range hosts {
+		p.Go(func(ctx context.Context) ([]models.Repository, error) {
+			client, err := newClient(host)
+			if err != nil {
+				return nil, err
+			}
+			return github.SearchRepositories(ctx, client, query)
+		})
+	}
+	results, err := p.Wait()
+	return flatten(results), err
+}
+
+// reloadCommand returns the command the fzf reload binding runs to replace the
+// list with the search results for the current query.
+func reloadCommand(opts *options) string {
+	return selfCommand(opts, cmdSearch, "--print", "--") + " {q}"
+}
+
+// selfCommand returns the shell command that runs gh-otui with the global
+// flags of this run and args, or "" if the executable is unknown.
+func selfCommand(opts *options, args ...string) string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	words := []string{cmd.ShellQuote(exe)}
+	for _, arg := range append(slices.Clone(opts.args), args...) {
+		words = append(words, cmd.ShellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}